@@ -0,0 +1,140 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// name of the config file in the home directory.
+const rcFile = ".goURLrc"
+
+// --config file, only registered so flag.Parse accepts it. It is looked
+// up in os.Args before parsing, see parseArgs.
+var configFile string
+
+// set flag defaults from the config file before flag.Parse, so the
+// command line still overrides them. Each line is "name = value" with
+// a flag name, a line of just "name" sets a boolean flag.
+// Empty lines and lines starting with # are skipped.
+func loadConfig(args []string) error {
+	name, explicit, set := parseArgs(args)
+	if name == "" {
+		return nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		if !explicit && os.IsNotExist(err) {
+			return nil
+		}
+		return errors.New(color.HiRedString("Unable to read config file: %v", err))
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value := line, "true"
+		if i := strings.Index(line, "="); i >= 0 {
+			key, value = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+			value = strings.Trim(value, `"`)
+		}
+		key = strings.TrimLeft(key, "-")
+		if key == "config" {
+			return errors.New(color.HiRedString("%s:%d: config can't be set in a config file", name, n))
+		}
+		// repeatable flags would add to the command line ones.
+		if setOnCommandLine(key, set) {
+			continue
+		}
+		if err := flag.Set(key, value); err != nil {
+			return errors.New(color.HiRedString("%s:%d: %v", name, n, err))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.New(color.HiRedString("Unable to read config file: %v", err))
+	}
+	return nil
+}
+
+// config file given with --config in args, or ~/.goURLrc, and the flags
+// set in args. explicit reports whether the file was given, a missing
+// ~/.goURLrc is fine.
+func parseArgs(args []string) (name string, explicit bool, set []*flag.Flag) {
+	// parse args like flag.Parse does, so flag values are skipped, but
+	// without setting anything except --config.
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	flag.VisitAll(func(f *flag.Flag) {
+		if f.Name != "config" {
+			fs.Var(ignoredValue{f.Value}, f.Name, "")
+		}
+	})
+	fs.StringVar(&name, "config", "", "")
+	// a bad flag is reported by flag.Parse, up to it --config counts.
+	_ = fs.Parse(args)
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name != "config" {
+			set = append(set, flag.Lookup(f.Name))
+		}
+	})
+	if name != "" {
+		return name, true, set
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", false, set
+	}
+	return filepath.Join(home, rcFile), false, set
+}
+
+// report whether the flag key, or another name of it like -d of --data,
+// is among the flags set on the command line.
+func setOnCommandLine(key string, set []*flag.Flag) bool {
+	f := flag.Lookup(key)
+	if f == nil {
+		return false
+	}
+	v := reflect.ValueOf(f.Value)
+	for _, s := range set {
+		w := reflect.ValueOf(s.Value)
+		switch {
+		case s.Name == f.Name:
+			return true
+		case v.Type() != w.Type():
+		case v.Kind() == reflect.Pointer:
+			if v.Pointer() == w.Pointer() {
+				return true
+			}
+		case v.Comparable() && v.Equal(w):
+			return true
+		}
+	}
+	return false
+}
+
+// flag value dropping what it is set to, it only tells the parser
+// whether the flag takes a value.
+type ignoredValue struct{ flag.Value }
+
+func (ignoredValue) Set(string) error { return nil }
+
+func (v ignoredValue) IsBoolFlag() bool {
+	b, ok := v.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+func init() {
+	flag.StringVar(&configFile, "config", "", fmt.Sprintf("read default flags from `file` instead of ~/%s", rcFile))
+}
@@ -1,11 +1,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net/url"
 	"os"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"goURL/parser"
@@ -14,46 +18,261 @@ import (
 
 func init() {
 	flag.StringVar(&utils.HttpMethod, "X", "GET", "HTTP method to use")
-	flag.BoolVar(&utils.HttpResponseHead, "I", false, "show response head and source code of page")
-	flag.BoolVar(&utils.HttpConnectInfo, "v", false, "show connect process")
+	for _, m := range methodShortcuts {
+		methodFlags[m] = flag.Bool(strings.ToLower(m), false, "same as -X "+m)
+	}
+	flag.BoolVar(&utils.CustomMethod, "request-custom", false, "allow a non-standard HTTP method in -X")
+	flag.BoolVar(&utils.HttpResponseHead, "I", false, "show response head only")
+	flag.Var(utils.VerboseFlag(1), "v", "show connect process, request and response head")
+	flag.Var(utils.VerboseFlag(2), "vv", "like -v, plus connection trace and the full body")
 	flag.BoolVar(&utils.ShowVersion, "V", false, "show goURL version")
+	flag.Var(utils.DataFlag(utils.DataPlain), "d", "HTTP request body to send, \"@file\" reads a file and \"@-\" stdin, several are joined with &")
+	flag.Var(utils.DataFlag(utils.DataPlain), "data", "same as -d")
+	flag.Var(utils.DataFlag(utils.DataRaw), "data-raw", "like -d, but send the data as is, also when it starts with @")
+	flag.StringVar(&utils.UserAgent, "A", "curl/7.77.0", "User-Agent to send, empty to omit it")
+	flag.StringVar(&utils.UserAgent, "user-agent", "curl/7.77.0", "same as -A")
+	flag.StringVar(&utils.OutputFile, "o", "", "write response body to file, \"-\" for stdout")
+	flag.StringVar(&utils.OutputFile, "output", "", "same as -o")
+	flag.BoolVar(&utils.AppendOutput, "append", false, "append the body to the -o file instead of replacing it")
+	flag.BoolVar(&utils.InsecureSkip, "k", false, "skip TLS certificate verification")
+	flag.BoolVar(&utils.InsecureSkip, "insecure", false, "same as -k")
+	flag.BoolVar(&utils.FollowRedirects, "L", false, "follow redirects")
+	flag.BoolVar(&utils.FollowRedirects, "location", false, "same as -L")
+	flag.IntVar(&utils.MaxRedirects, "max-redirs", 50, "maximum number of redirects to follow, -1 for unlimited")
+	flag.BoolVar(&utils.ShowTiming, "timing", false, "show request timing breakdown")
+	flag.DurationVar(&utils.MaxTime, "max-time", 0, "maximum time allowed for the whole request, e.g. 10s")
+	flag.DurationVar(&utils.ConnectTimeout, "connect-timeout", 30*time.Second, "maximum time to connect, also within --max-time")
+	flag.StringVar(&utils.BasicAuth, "u", "", "basic auth \"user:password\", prompts for the password if omitted")
+	flag.StringVar(&utils.BasicAuth, "user", "", "same as -u")
+	flag.BoolVar(&utils.Digest, "digest", false, "use digest auth with the -u credentials, answering the server's 401 challenge")
+	flag.StringVar(&utils.BearerToken, "bearer", "", "bearer token for the Authorization header")
+	flag.BoolVar(&utils.Netrc, "netrc", false, "basic auth for the URL host from ~/.netrc, unless -u or --bearer is given")
+	flag.StringVar(&utils.NetrcFile, "netrc-file", "", "like --netrc, but read `file`")
+	flag.StringVar(&utils.AWSSigV4, "aws-sigv4", "", "sign requests with AWS Signature V4 for `service:region`, e.g. s3:us-east-1")
+	flag.StringVar(&utils.AWSAccessKey, "aws-access-key", "", "AWS access key ID, AWS_ACCESS_KEY_ID by default")
+	flag.StringVar(&utils.AWSSecretKey, "aws-secret-key", "", "AWS secret access key, AWS_SECRET_ACCESS_KEY by default")
+	flag.StringVar(&utils.AWSSessionToken, "aws-session-token", "", "AWS session token, AWS_SESSION_TOKEN by default")
+	flag.BoolVar(&utils.Silent, "s", false, "silent mode, print only the response body")
+	flag.BoolVar(&utils.Silent, "silent", false, "same as -s")
+	flag.BoolVar(&utils.FailOnError, "f", false, "fail with exit code 22 on HTTP errors, without printing the body")
+	flag.BoolVar(&utils.FailOnError, "fail", false, "same as -f")
+	flag.BoolVar(&utils.IPv4Only, "4", false, "connect over IPv4 only")
+	flag.BoolVar(&utils.IPv6Only, "6", false, "connect over IPv6 only")
+	flag.Var(&utils.Resolve, "resolve", "use ADDRESS for HOST:PORT, as HOST:PORT:ADDRESS (repeatable)")
+	flag.StringVar(&utils.Cookie, "b", "", "send cookies \"name=value; name2=value2\" or read them from a Netscape cookie file")
+	flag.StringVar(&utils.Cookie, "cookie", "", "same as -b")
+	flag.StringVar(&utils.CookieJar, "c", "", "write received cookies to file")
+	flag.StringVar(&utils.CookieJar, "cookie-jar", "", "same as -c")
+	flag.BoolVar(&utils.Compressed, "compressed", false, "request a compressed response and decompress it")
+	flag.BoolVar(&utils.PrettyJSON, "j", false, "pretty-print JSON response bodies, shows the full body")
+	flag.BoolVar(&utils.PrettyJSON, "pretty", false, "same as -j")
+	flag.IntVar(&utils.Retry, "retry", 0, "retry the request N times on connection errors")
+	flag.DurationVar(&utils.RetryDelay, "retry-delay", time.Second, "wait before the first retry, doubled after each one")
+	flag.IntVar(&utils.Repeat, "repeat", 0, "send the request `n` times with one status line each, -1 until Ctrl-C")
+	flag.DurationVar(&utils.RepeatInterval, "repeat-interval", time.Second, "wait between --repeat requests")
+	flag.BoolVar(&utils.RetryAllErrors, "retry-all-errors", false, "also retry on 5xx responses")
+	flag.IntVar(&utils.Parallel, "P", 1, "fetch up to N URLs in parallel")
+	flag.IntVar(&utils.Parallel, "parallel", 1, "same as -P")
+	flag.BoolVar(&utils.NoColor, "no-color", false, "disable colored output")
+	flag.StringVar(&utils.WriteOut, "w", "", "print `format` after the request, e.g. '%{http_code} %{time_total}\\n'")
+	flag.StringVar(&utils.WriteOut, "write-out", "", "same as -w")
+	flag.StringVar(&utils.ProxyURL, "x", "", "use http, https or socks5 proxy `URL`, overriding the environment")
+	flag.StringVar(&utils.ProxyURL, "proxy", "", "same as -x")
+	flag.StringVar(&utils.NoProxy, "noproxy", "", "comma separated hosts to reach without proxy, \"*\" for all")
+	flag.StringVar(&utils.ClientCert, "cert", "", "client certificate `file` (PEM) for mutual TLS")
+	flag.StringVar(&utils.ClientKey, "key", "", "private key `file` (PEM) of --cert, if not in the certificate file")
+	flag.StringVar(&utils.CACert, "cacert", "", "verify the server against the CA certificates in `file` (PEM)")
+	flag.StringVar(&utils.TLSMin, "tls-min", "", "lowest TLS `version` to use, 1.0, 1.1, 1.2 or 1.3 (default 1.2, or --tls-max when lower)")
+	flag.StringVar(&utils.TLSMax, "tls-max", "", "highest TLS `version` to use, 1.0, 1.1, 1.2 or 1.3")
+	flag.BoolVar(&utils.ExitFromStatus, "exit-code-from-status", false, "exit with 4 for a 4xx and 5 for a 5xx status, 0 otherwise")
+	flag.StringVar(&utils.Range, "r", "", "get only the byte `range`, e.g. 0-1023")
+	flag.StringVar(&utils.Range, "range", "", "same as -r")
+	flag.Var(&utils.LimitRate, "limit-rate", "limit the download speed to `speed` bytes per second, e.g. 100k or 1m")
+	flag.Var(&utils.MaxFileSize, "max-filesize", "abort when the body is larger than `size` bytes, e.g. 10m")
+	flag.BoolVar(&utils.NoDecode, "no-decode", false, "show the body as raw bytes instead of converting its charset to UTF-8")
+	flag.Var(utils.DataFlag(utils.DataURLEncoded), "data-urlencode", "URL-encode and send form `data` \"name=value\" or \"name@file\" (repeatable)")
+	flag.Var(&utils.FormFields, "F", "send multipart form `field` \"name=value\" or \"name=@file\" (repeatable)")
+	flag.StringVar(&utils.JSONData, "json", "", "send JSON `data` (or @file) with JSON Content-Type and Accept headers")
+	flag.BoolVar(&utils.ShowSecrets, "show-secrets", false, "don't redact Authorization and Cookie headers under -vv")
+	flag.BoolVar(&utils.HeadJSON, "head-json", false, "print the status and response headers as JSON, without the body")
+	flag.BoolVar(&utils.OutputJSON, "output-json", false, "print request, response, timing and TLS details as one JSON document instead of the body")
+	flag.BoolVar(&utils.DryRun, "dry-run", false, "print the requests that would be sent, head and body, without sending them")
+	flag.BoolVar(&utils.CurlCommand, "curl", false, "print an equivalent curl command for each URL instead of sending the request")
+	flag.BoolVar(&utils.Raw, "raw", false, "print the response head and body as received, without colors or decoding")
+	flag.BoolVar(&utils.HTTP11, "http1.1", false, "use HTTP/1.1 only, never HTTP/2")
+	flag.BoolVar(&utils.HTTP3, "http3", false, "use HTTP/3 over QUIC, failing if the server doesn't support it")
+	flag.StringVar(&utils.UnixSocket, "unix-socket", "", "connect through the unix socket at `path` instead of the URL host")
+	flag.BoolVar(&utils.NoKeepAlive, "no-keepalive", false, "don't reuse connections between requests")
+	flag.DurationVar(&utils.KeepAliveTime, "keepalive-time", 90*time.Second, "how long an idle connection is kept for reuse")
+	flag.DurationVar(&utils.Expect100Timeout, "expect100-timeout", time.Second, "wait this long for 100 Continue before sending a large body anyway")
+	flag.BoolVar(&utils.NoExpect100, "no-expect100", false, "don't send Expect: 100-continue, also when given with -H")
+	flag.DurationVar(&utils.TLSTimeout, "tls-handshake-timeout", 10*time.Second, "maximum time for the TLS handshake, or the QUIC one with --http3")
+	flag.IntVar(&utils.MaxIdleConns, "max-idle-conns", 100, "idle connections kept for reuse over all hosts, 0 for no limit")
+	flag.StringVar(&utils.DumpHeader, "D", "", "write the response status line and headers to `file`, \"-\" for stdout")
+	flag.StringVar(&utils.DumpHeader, "dump-header", "", "same as -D")
+	flag.BoolVar(&utils.ShowStats, "stats", false, "print bytes sent and received, status and elapsed time after the request")
+	flag.StringVar(&utils.HostHeader, "host-header", "", "send `host` in the Host header, still connecting to the URL host")
+	flag.Var(&utils.ConnectTo, "connect-to", "connect to HOST2:PORT2 for requests to HOST1:PORT1, as HOST1:PORT1:HOST2:PORT2 (repeatable)")
+	flag.Var(&utils.PreviewLines, "preview-lines", "show the first and last `lines` of the body, as HEAD or HEAD,TAIL")
+	flag.Var(&utils.PreviewBytes, "preview-bytes", "show the first `size` bytes of the body instead of lines, e.g. 200 or 4k")
+	flag.IntVar(&utils.MaxLineLength, "max-line-length", 0, "cut body lines longer than `n` characters, 0 uses the terminal width, -1 never cuts")
+	flag.StringVar(&utils.Interface, "interface", "", "send from the interface `name` or IP address")
+	flag.IntVar(&utils.LocalPort, "local-port", 0, "send from the local `port`")
+	flag.StringVar(&utils.DNSServer, "dns-server", "", "resolve host names with the DNS server at `addr`, port 53 by default")
+	flag.StringVar(&utils.ETagSave, "etag-save", "", "save the response ETag and Last-Modified to `file`")
+	flag.StringVar(&utils.ETagCompare, "etag-compare", "", "send If-None-Match and If-Modified-Since from `file`, written by --etag-save")
+	flag.BoolVar(&utils.OptionsMode, "options", false, "send an OPTIONS request and show the Allow and CORS headers")
+	flag.StringVar(&utils.TraceASCII, "trace-ascii", "", "dump the request and response as sent and received to `file`, \"-\" for stdout")
+	flag.BoolVar(&utils.TraceTime, "trace-time", false, "start verbose lines with the time since the request started")
+	flag.Var(&utils.SHA256, "sha256", "check the SHA-256 of the body against `hex`, --sha256= prints it")
+	flag.Var(&utils.MD5, "md5", "check the MD5 of the body against `hex`, --md5= prints it")
+	flag.Var(&utils.URLArgs, "url", "`URL` to fetch, like a positional one (repeatable)")
+	flag.BoolVar(&utils.WebSocket, "ws", false, "open a WebSocket, also for http URLs, sending stdin lines as messages")
+	flag.BoolVar(&utils.Stream, "stream", false, "print the whole body as it arrives instead of a preview, without buffering it")
+	flag.BoolVar(&utils.SSE, "sse", false, "show the body as Server-Sent Events as they arrive, also without a text/event-stream Content-Type")
+	flag.BoolVar(&utils.WebSocket, "websocket", false, "same as --ws")
+	flag.BoolVar(&utils.CompressRequest, "compressed-request", false, "gzip the request body and send it with Content-Encoding: gzip")
+	flag.Var(&utils.HeaderFilter, "header-filter", "show only the response header `name` (repeatable)")
+	flag.StringVar(&utils.GrepHeader, "grep-header", "", "show only response headers whose \"Name: value\" line matches `regexp`")
+	flag.BoolVar(&utils.NoSortHeaders, "no-sort-headers", false, "show response headers in the order received, plain HTTP/1.x only, others stay sorted")
+	flag.BoolVar(&utils.NoExpand, "no-expand", false, "send -H values as given, without expanding ${VAR}")
+	flag.Var(&utils.Trailers, "trailer", "send trailer \"Name: Value\" after the body (repeatable), which makes the body chunked")
+	flag.BoolVar(&utils.GlobOff, "globoff", false, "take [] and {} in URLs literally instead of expanding them into several URLs")
+	flag.BoolVar(&utils.GlobOff, "g", false, "same as --globoff")
+	flag.BoolVar(&utils.PathAsIs, "path-as-is", false, "send the URL path exactly as given, without escaping it again")
+	flag.Var(&utils.CustomHeaders, "H", "add request header \"Name: Value\" (repeatable), ${VAR} is taken from the environment")
 	flag.Usage = usage
 }
 
-func usage()  {
-	_, _ = fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] URL\n\n", os.Args[0])
+func usage() {
+	_, _ = fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] URL...\n\n", os.Args[0])
 	_, _ = fmt.Fprintln(os.Stderr, "OPTIONS:")
 	flag.PrintDefaults()
 }
 
 func main() {
+	// defaults from the config file, the command line overrides them.
+	if err := loadConfig(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
 	// parse command-line flags from os.Args[1:].
 	flag.Parse()
 
+	// color already turns itself off for NO_COLOR and when
+	// stdout isn't a terminal.
+	if utils.NoColor {
+		color.NoColor = true
+	}
+
 	// show goURL version or warning.
 	if utils.ShowVersion {
 		if utils.Version == "Dev" {
 			// print information with red color !
 			color.HiRed("This is a %s version! Please do not use in a product environment! \n (runtime: %s)\n", utils.Version, runtime.Version())
 		} else {
-			fmt.Printf("goURL version: %s \n(runtime: %s)\n", utils.VisitURL, runtime.Version())
+			fmt.Printf("goURL version: %s \n(runtime: %s)\n", utils.Version, runtime.Version())
 		}
 	}
 
-	args := flag.Args()
-	if len(args) != 1 {
+	// send a body with POST like curl does, unless -X is given.
+	utils.HttpMethodSet = isFlagSet("X")
+	if err := applyMethodShortcut(); err != nil {
+		log.Fatal(err)
+	}
+	hasData := utils.HttpData != "" || len(utils.DataURLEncode) > 0 || len(utils.FormFields) > 0 || utils.JSONData != ""
+	if hasData && !utils.HttpMethodSet {
+		utils.HttpMethod = "POST"
+	}
+
+	// --url entries come first, flag.Parse stopped at the positional ones.
+	args := append(append([]string{}, utils.URLArgs...), flag.Args()...)
+	if len(args) == 0 {
 		flag.Usage()
 		log.Fatalf(color.HiRedString("Too few arguments"))
 	}
 
-	// parse url argument.
-	url, err := parser.ParseURL(args[0])
-	if err != nil {
-		log.Fatalf(color.HiRedString("Something wrong while parsing url:" + err.Error()))
+	// expand [1-10] and {a,b} globs like curl, capped over all arguments.
+	if !utils.GlobOff {
+		var expanded []string
+		for _, arg := range args {
+			list, err := parser.ExpandGlob(arg, parser.MaxGlobURLs-len(expanded))
+			if err != nil {
+				log.Fatal(err)
+			}
+			expanded = append(expanded, list...)
+		}
+		args = expanded
+	}
+
+	// parse url arguments.
+	urls := make([]*url.URL, 0, len(args))
+	parse := parser.ParseURL
+	if utils.PathAsIs {
+		parse = parser.ParseURLPathAsIs
 	}
-	// do connect with target URL.
-	err = utils.VisitURL(url)
+	for _, arg := range args {
+		u, err := parse(arg)
+		if err != nil {
+			log.Fatalf(color.HiRedString("Something wrong while parsing url:" + err.Error()))
+		}
+		urls = append(urls, u)
+	}
+	// do connect with target URLs.
+	err := utils.VisitURLs(urls)
 	if err != nil {
-		log.Fatalf(color.HiRedString(err.Error()))
+		log.Print(color.HiRedString(err.Error()))
+		os.Exit(exitCode(err))
 	}
 }
+
+// exit code for an error of VisitURLs. HTTP errors exit with 22 under
+// --fail like curl, and with 4 or 5 under --exit-code-from-status.
+func exitCode(err error) int {
+	var statusErr *utils.StatusError
+	if errors.As(err, &statusErr) {
+		if utils.FailOnError {
+			return 22
+		}
+		return statusErr.Code / 100
+	}
+	return 1
+}
+
+// methods with a flag of their own, --get for -X GET and so on.
+var methodShortcuts = []string{"GET", "HEAD", "POST", "PUT", "DELETE", "PATCH"}
+
+var methodFlags = make(map[string]*bool, len(methodShortcuts))
+
+// set the method of a --get style flag, at most one of them and only
+// if -X doesn't ask for another method.
+func applyMethodShortcut() error {
+	method := ""
+	for _, m := range methodShortcuts {
+		if !*methodFlags[m] {
+			continue
+		}
+		if method != "" {
+			return errors.New(color.HiRedString("--%s and --%s can't be used together", strings.ToLower(method), strings.ToLower(m)))
+		}
+		method = m
+	}
+	if method == "" {
+		return nil
+	}
+	if utils.HttpMethodSet && !strings.EqualFold(utils.HttpMethod, method) {
+		return errors.New(color.HiRedString("--%s can't be used together with -X %s", strings.ToLower(method), utils.HttpMethod))
+	}
+	utils.HttpMethod, utils.HttpMethodSet = method, true
+	return nil
+}
+
+// report whether a flag was set on the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
@@ -0,0 +1,152 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// MaxGlobURLs caps the URLs all globs of a command line expand to.
+const MaxGlobURLs = 10000
+
+// ExpandGlob expands the curl style globs of uri, sets like "{a,b,c}" and
+// ranges like "[1-10]", "[001-100:10]" or "[a-z]", into the URLs they
+// stand for, the leftmost glob changing slowest. "\[", "\]", "\{" and
+// "\}" are literal, as is an IPv6 address like "[::1]". It fails when
+// there are more than limit URLs.
+func ExpandGlob(uri string, limit int) ([]string, error) {
+	var globs [][]string
+	var lit strings.Builder
+	// a literal run is a glob of one.
+	flush := func() {
+		if lit.Len() > 0 {
+			globs = append(globs, []string{lit.String()})
+			lit.Reset()
+		}
+	}
+	for i := 0; i < len(uri); i++ {
+		c := uri[i]
+		switch {
+		case c == '\\' && i+1 < len(uri) && strings.IndexByte("[]{}", uri[i+1]) >= 0:
+			i++
+			lit.WriteByte(uri[i])
+		case c == '{' || c == '[':
+			end := strings.IndexByte(uri[i:], closing(c))
+			if end < 0 {
+				return nil, errors.New(color.HiRedString("Unmatched %c in URL %q, --globoff takes it literally", c, uri))
+			}
+			body := uri[i+1 : i+end]
+			if strings.ContainsAny(body, "{[") {
+				return nil, errors.New(color.HiRedString("Nested glob in URL %q", uri))
+			}
+			i += end
+			if c == '[' && isIPv6(body) {
+				lit.WriteString("[" + body + "]")
+				continue
+			}
+			alts := strings.Split(body, ",")
+			if c == '[' {
+				var err error
+				if alts, err = expandRange(body); err != nil {
+					return nil, errors.New(color.HiRedString("Bad range [%s] in URL %q: %v", body, uri, err))
+				}
+			}
+			flush()
+			globs = append(globs, alts)
+		case c == '}' || c == ']':
+			return nil, errors.New(color.HiRedString("Unmatched %c in URL %q, --globoff takes it literally", c, uri))
+		default:
+			lit.WriteByte(c)
+		}
+	}
+	flush()
+
+	n := 1
+	for _, g := range globs {
+		if n *= len(g); n > limit {
+			return nil, errors.New(color.HiRedString("URL %q expands to more than %d URLs", uri, limit))
+		}
+	}
+	urls := []string{""}
+	for _, g := range globs {
+		next := make([]string, 0, len(urls)*len(g))
+		for _, u := range urls {
+			for _, s := range g {
+				next = append(next, u+s)
+			}
+		}
+		urls = next
+	}
+	return urls, nil
+}
+
+func closing(c byte) byte {
+	if c == '{' {
+		return '}'
+	}
+	return ']'
+}
+
+// report whether s, the text between brackets, is an IPv6 address with
+// an optional zone.
+func isIPv6(s string) bool {
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.Contains(s, ":") && net.ParseIP(s) != nil
+}
+
+// values of the range "a-b" or "a-b:step", numbers or letters. A number
+// written with leading zeros sets the width for all.
+func expandRange(r string) ([]string, error) {
+	step := 1
+	if i := strings.LastIndexByte(r, ':'); i >= 0 {
+		n, err := strconv.Atoi(r[i+1:])
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("step %q isn't a positive number", r[i+1:])
+		}
+		step, r = n, r[:i]
+	}
+	from, to, ok := strings.Cut(r, "-")
+	if !ok {
+		return nil, errors.New("want a range like 1-10 or a-z")
+	}
+
+	var values []string
+	if len(from) == 1 && len(to) == 1 && isLetter(from[0]) && isLetter(to[0]) {
+		if isLower(from[0]) != isLower(to[0]) || from[0] > to[0] {
+			return nil, fmt.Errorf("%s doesn't come before %s", from, to)
+		}
+		for c := int(from[0]); c <= int(to[0]); c += step {
+			values = append(values, string(rune(c)))
+		}
+		return values, nil
+	}
+	a, err := strconv.Atoi(from)
+	if err != nil || a < 0 {
+		return nil, fmt.Errorf("%q isn't a number or letter", from)
+	}
+	b, err := strconv.Atoi(to)
+	if err != nil || b < a {
+		return nil, fmt.Errorf("%q isn't a number from %d on", to, a)
+	}
+	width := 0
+	if len(from) > 1 && from[0] == '0' {
+		width = len(from)
+	}
+	for i := a; i <= b; i += step {
+		values = append(values, fmt.Sprintf("%0*d", width, i))
+		// the caller's limit doesn't see the ranges themselves.
+		if len(values) > MaxGlobURLs {
+			return nil, fmt.Errorf("more than %d values", MaxGlobURLs)
+		}
+	}
+	return values, nil
+}
+
+func isLetter(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
+func isLower(c byte) bool  { return 'a' <= c && c <= 'z' }
@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExpandGlob(t *testing.T) {
+	tests := []struct {
+		uri  string
+		want []string
+	}{
+		{"http://h/a", []string{"http://h/a"}},
+		{"http://h/f[1-3].txt", []string{"http://h/f1.txt", "http://h/f2.txt", "http://h/f3.txt"}},
+		{"http://h/[08-10]", []string{"http://h/08", "http://h/09", "http://h/10"}},
+		{"http://h/[1-10:4]", []string{"http://h/1", "http://h/5", "http://h/9"}},
+		{"http://h/[a-c]", []string{"http://h/a", "http://h/b", "http://h/c"}},
+		{"http://h/[A-E:2]", []string{"http://h/A", "http://h/C", "http://h/E"}},
+		{"http://h/{x,y}", []string{"http://h/x", "http://h/y"}},
+		{"http://h/{a,}/", []string{"http://h/a/", "http://h//"}},
+		// the leftmost glob changes slowest.
+		{"http://{a,b}/[1-2]", []string{"http://a/1", "http://a/2", "http://b/1", "http://b/2"}},
+		{`http://h/\[1\]\{x\}`, []string{"http://h/[1]{x}"}},
+		{"http://[::1]:8080/[1-2]", []string{"http://[::1]:8080/1", "http://[::1]:8080/2"}},
+		{"http://[fe80::1%25eth0]/", []string{"http://[fe80::1%25eth0]/"}},
+	}
+	for _, tt := range tests {
+		got, err := ExpandGlob(tt.uri, MaxGlobURLs)
+		if err != nil {
+			t.Errorf("ExpandGlob(%q): %v", tt.uri, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ExpandGlob(%q) = %q, want %q", tt.uri, got, tt.want)
+		}
+	}
+}
+
+func TestExpandGlobErrors(t *testing.T) {
+	for _, uri := range []string{
+		"http://h/[1-",
+		"http://h/{a,b",
+		"http://h/a]",
+		"http://h/a}",
+		"http://h/{a,[1-2]}",
+		"http://h/[3-1]",
+		"http://h/[z-a]",
+		"http://h/[a-Z]",
+		"http://h/[1-5:0]",
+		"http://h/[1-5:x]",
+		"http://h/[1,2]",
+		"http://h/[-1-5]",
+	} {
+		if got, err := ExpandGlob(uri, MaxGlobURLs); err == nil {
+			t.Errorf("ExpandGlob(%q) = %q, want an error", uri, got)
+		}
+	}
+}
+
+func TestExpandGlobLimit(t *testing.T) {
+	if got, err := ExpandGlob("http://h/[1-10]{a,b}", 20); err != nil || len(got) != 20 {
+		t.Errorf("20 URLs within a limit of 20: %d URLs, %v", len(got), err)
+	}
+	if _, err := ExpandGlob("http://h/[1-10]{a,b}", 19); err == nil {
+		t.Error("20 URLs over a limit of 19: no error")
+	}
+	if _, err := ExpandGlob("http://h/[1-100000000]", MaxGlobURLs); err == nil {
+		t.Error("a huge range: no error")
+	}
+}
@@ -2,68 +2,75 @@ package parser
 
 import (
 	"errors"
-	"github.com/fatih/color"
 	"net/url"
+	"strconv"
 	"strings"
-)
 
-func ParseURL(uri string) (*url.URL, error) {
-	// we can only solve "*://url" and "//url".
-	// if user types incorrect url unfortunately, like "///url",
-	// it's a bad news for our program.
-	// TODO: think about a solution for this.
-	// one way to solve this is force check weather url is correct
+	"github.com/fatih/color"
+)
 
-	// If we choose to check all kinds of urls, it's too complex
-	// and hard to think about all situations. Anyway it's a bad
-	// practice.
-	// TODO: Solution One
-	// if !strings.Contains(uri, "://") && !strings.HasPrefix(uri, "//") {
-	//	 uri = "//" + uri
-	// }
+// schemes we can fetch.
+var schemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"ws":    true,
+	"wss":   true,
+}
 
-	// we use defend coding to avoid complex situations.
-	// TODO: Solution Two
-	uri, err := checkURL(uri)
-	if err != nil {
-		return nil, err
+// ParseURL parses uri, which may lack a scheme like "example.com" or
+// "localhost:8080/path", into a URL ready to be dialed. A missing
+// scheme means http.
+func ParseURL(uri string) (*url.URL, error) {
+	uri = strings.TrimSpace(uri)
+	if uri == "" {
+		return nil, errors.New(color.HiRedString("Missing URL"))
 	}
-
-	// special behave when url is a localhost
-	if localhost := strings.Contains(uri, "localhost"); localhost {
-		uri = "http://" + uri
+	// "//host" is a URL without scheme too.
+	if !strings.Contains(uri, "://") {
+		uri = "http://" + strings.TrimPrefix(uri, "//")
 	}
 
-	url, err := url.Parse(uri)
+	u, err := url.Parse(uri)
 	if err != nil {
 		return nil, err
 	}
-
-	if url.Scheme == "" {
-		url.Scheme = "http"
-		if !strings.HasSuffix(url.Host, ":80") {
-			url.Scheme += "s"
+	u.Scheme = strings.ToLower(u.Scheme)
+	if !schemes[u.Scheme] {
+		return nil, errors.New(color.HiRedString("Unsupported scheme %q, want http, https, ws or wss", u.Scheme))
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New(color.HiRedString("URL %q has no host", uri))
+	}
+	if port := u.Port(); port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+			return nil, errors.New(color.HiRedString("Bad port %q in URL %q", port, uri))
 		}
 	}
-	return url, nil
+	return u, nil
 }
 
-// check input url format.
-func checkURL(url string) (string, error) {
-	// The correct url types is:
-	// example.com
-	// http://example.com
-	// https://example.com
-	// TODO: we can't check domain perfect
-	if !strings.HasPrefix(url, "/") &&
-		!strings.Contains(url, "//") &&
-		!strings.Contains(url, "http") {
-		return url, nil
+// ParseURLPathAsIs is ParseURL keeping the path exactly as written in
+// u.RawPath, also when it isn't a valid escaping, for --path-as-is.
+func ParseURLPathAsIs(uri string) (*url.URL, error) {
+	u, err := ParseURL(uri)
+	if err != nil {
+		return nil, err
+	}
+	uri = strings.TrimSpace(uri)
+	if i := strings.Index(uri, "://"); i >= 0 {
+		uri = uri[i+3:]
+	} else {
+		uri = strings.TrimPrefix(uri, "//")
+	}
+	// the path starts after the host and ends at the query or fragment.
+	i := strings.IndexAny(uri, "/?#")
+	if i < 0 || uri[i] != '/' {
+		return u, nil
 	}
-	if strings.Contains(url, "//") &&
-		(strings.HasPrefix(url, "http:") ||
-			strings.HasPrefix(url, "https:")) {
-		return url, nil
+	path := uri[i:]
+	if j := strings.IndexAny(path, "?#"); j >= 0 {
+		path = path[:j]
 	}
-	return "", errors.New(color.HiRedString("URL using bad/illegal format or missing URL"))
+	u.RawPath = path
+	return u, nil
 }
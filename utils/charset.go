@@ -0,0 +1,34 @@
+package utils
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// transcode a body to UTF-8 using the charset of its Content-Type.
+// The body is returned unchanged for UTF-8, unknown charsets or --no-decode.
+func decodeCharset(resp *http.Response, body []byte) []byte {
+	if NoDecode {
+		return body
+	}
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil {
+		return body
+	}
+	name := strings.ToLower(params["charset"])
+	if name == "" || name == "utf-8" || name == "utf8" || name == "us-ascii" {
+		return body
+	}
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return body
+	}
+	decoded, err := enc.NewDecoder().Bytes(body)
+	if err != nil {
+		return body
+	}
+	return decoded
+}
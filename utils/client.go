@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/cookiejar"
+
+	"github.com/fatih/color"
+)
+
+// client shared by all URLs of a run, so connections are pooled.
+func newClient() (*http.Client, error) {
+	dial, err := newDialContext()
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := newTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	tr := &http.Transport{
+		DialContext:           dial,
+		MaxIdleConns:          MaxIdleConns,
+		IdleConnTimeout:       KeepAliveTime,
+		TLSHandshakeTimeout:   TLSTimeout,
+		ExpectContinueTimeout: Expect100Timeout,
+		ForceAttemptHTTP2:     true,
+		DisableKeepAlives:     NoKeepAlive,
+		DisableCompression:    Raw,
+		TLSClientConfig:       tlsConfig,
+	}
+	// a non-nil empty map, nil would let net/http enable HTTP/2 again.
+	if HTTP11 {
+		tr.ForceAttemptHTTP2 = false
+		tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+
+	if err := setProxy(tr, dial); err != nil {
+		return nil, err
+	}
+
+	var rt http.RoundTripper = tr
+	if HTTP3 {
+		switch {
+		case HTTP11:
+			return nil, errors.New(color.HiRedString("--http3 and --http1.1 can't be used together"))
+		case ProxyURL != "":
+			return nil, errors.New(color.HiRedString("--http3 can't be used with a proxy"))
+		}
+		h3, err := newHTTP3Transport(tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+		rt = h3
+	}
+
+	client := &http.Client{
+		Transport:     rt,
+		CheckRedirect: checkRedirect,
+		Timeout:       MaxTime,
+	}
+	// keep cookies set during redirects, like curl's cookie engine.
+	if Cookie != "" || CookieJar != "" {
+		client.Jar, _ = cookiejar.New(nil)
+	}
+	return client, nil
+}
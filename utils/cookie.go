@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// add -b cookies to the request. The value is either
+// "name=value; name2=value2" or a Netscape cookie file.
+func addCookies(req *http.Request, cookie string) error {
+	if strings.Contains(cookie, "=") {
+		req.Header.Set("Cookie", cookie)
+		return nil
+	}
+	cookies, err := readCookieFile(cookie, req.URL)
+	if err != nil {
+		return err
+	}
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	return nil
+}
+
+// read the cookies in a Netscape cookie file which apply to u.
+func readCookieFile(name string, u *url.URL) ([]*http.Cookie, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, errors.New(color.HiRedString("Unable to read cookie file: %v", err))
+	}
+	defer f.Close()
+
+	host := u.Hostname()
+	path := u.Path
+	if path == "" {
+		path = "/"
+	}
+	now := time.Now().Unix()
+
+	var cookies []*http.Cookie
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimPrefix(scanner.Text(), "#HttpOnly_")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		// domain, subdomains, path, secure, expiry, name, value
+		fields := strings.Split(line, "\t")
+		if len(fields) != 7 {
+			continue
+		}
+		domain := strings.TrimPrefix(fields[0], ".")
+		if host != domain && !(fields[1] == "TRUE" && strings.HasSuffix(host, "."+domain)) {
+			continue
+		}
+		if !strings.HasPrefix(path, fields[2]) {
+			continue
+		}
+		if fields[3] == "TRUE" && u.Scheme != "https" {
+			continue
+		}
+		// zero expiry is a session cookie.
+		if expiry, _ := strconv.ParseInt(fields[4], 10, 64); expiry != 0 && expiry < now {
+			continue
+		}
+		cookies = append(cookies, &http.Cookie{Name: fields[5], Value: fields[6]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.New(color.HiRedString("Unable to read cookie file: %v", err))
+	}
+	return cookies, nil
+}
+
+// cookies set by the responses of all URLs, for -c, in the order they
+// were first set. A later cookie of the same domain, path and name
+// replaces an earlier one, an expired one removes it.
+type cookieFile struct {
+	mu    sync.Mutex
+	lines map[string]string
+	keys  []string
+}
+
+var savedCookies = &cookieFile{lines: make(map[string]string)}
+
+// keep the cookies set by resp and the redirects before it.
+func (f *cookieFile) add(resp *http.Response) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// the first response set its cookies first.
+	var responses []*http.Response
+	for r := resp; r != nil; r = r.Request.Response {
+		responses = append([]*http.Response{r}, responses...)
+	}
+	for _, r := range responses {
+		for _, c := range r.Cookies() {
+			line := netscapeCookie(c, r.Request.URL)
+			fields := strings.Split(line, "\t")
+			key := strings.Join([]string{strings.TrimPrefix(fields[0], "#HttpOnly_"), fields[2], fields[5]}, "\t")
+			if _, ok := f.lines[key]; !ok {
+				f.keys = append(f.keys, key)
+			}
+			f.lines[key] = line
+			if c.MaxAge < 0 || !c.Expires.IsZero() && c.Expires.Before(time.Now()) {
+				f.lines[key] = ""
+			}
+		}
+	}
+}
+
+// write the cookies kept to a Netscape cookie file, like curl -c.
+func (f *cookieFile) write(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var b strings.Builder
+	b.WriteString("# Netscape HTTP Cookie File\n")
+	for _, k := range f.keys {
+		b.WriteString(f.lines[k])
+	}
+	if err := os.WriteFile(name, []byte(b.String()), 0600); err != nil {
+		return errors.New(color.HiRedString("Unable to write cookie file: %v", err))
+	}
+	return nil
+}
+
+// format one cookie as a Netscape cookie file line.
+func netscapeCookie(c *http.Cookie, u *url.URL) string {
+	domain, subdomains := u.Hostname(), "FALSE"
+	if c.Domain != "" {
+		domain, subdomains = "."+strings.TrimPrefix(c.Domain, "."), "TRUE"
+	}
+	if c.HttpOnly {
+		domain = "#HttpOnly_" + domain
+	}
+	path := c.Path
+	if path == "" {
+		path = "/"
+	}
+	secure := "FALSE"
+	if c.Secure {
+		secure = "TRUE"
+	}
+	var expiry int64
+	switch {
+	case c.MaxAge > 0:
+		expiry = time.Now().Unix() + int64(c.MaxAge)
+	case !c.Expires.IsZero():
+		expiry = c.Expires.Unix()
+	}
+	return fmt.Sprintf("%s\t%s\t%s\t%s\t%d\t%s\t%s\n", domain, subdomains, path, secure, expiry, c.Name, c.Value)
+}
@@ -0,0 +1,252 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// print a curl command for each of urls, sending what goURL would, for
+// --curl. Nothing is sent.
+func showCurl(w io.Writer, method string, urls []*url.URL) error {
+	for _, u := range urls {
+		req, err := newRequest(method, u, HttpData)
+		if err != nil {
+			return err
+		}
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		cmd, omitted := curlCommand(req)
+		// on stderr, the command may be piped to a shell.
+		for _, o := range omitted {
+			bannerf(color.Error, "%s\n", color.YellowString("Warning: %s has no curl equal and is left out", o))
+		}
+		fprintf(w, "%s\n", cmd)
+	}
+	return nil
+}
+
+// curl command line for req, as built by newRequest, and the flags
+// curl has an equal of. omitted lists the flags it has none for.
+func curlCommand(req *http.Request) (cmd string, omitted []string) {
+	args := []string{"curl"}
+	add := func(a ...string) { args = append(args, a...) }
+	// arguments already quoted for the shell.
+	quoted := make(map[int]bool)
+
+	hasBody := HttpData != "" || len(FormFields) > 0
+	switch {
+	case req.Method == http.MethodHead:
+		add("-I")
+	case req.Method == http.MethodGet && !hasBody, req.Method == http.MethodPost && hasBody:
+		// what curl picks by itself.
+	default:
+		add("-X", req.Method)
+	}
+
+	h := req.Header.Clone()
+	// -u, -b and --compressed say it shorter than their headers.
+	if BasicAuth != "" && !Digest {
+		h.Del("Authorization")
+	}
+	if BasicAuth != "" {
+		if Digest {
+			add("--digest")
+		}
+		// curl asks for a password missing after the user as well.
+		if user, _, _ := strings.Cut(BasicAuth, ":"); passwordPrompted {
+			add("-u", user)
+		} else {
+			add("-u", BasicAuth)
+		}
+	}
+	if netrc != nil && BasicAuth == "" && BearerToken == "" {
+		h.Del("Authorization")
+		if NetrcFile != "" {
+			add("--netrc-file", NetrcFile)
+		} else {
+			add("--netrc")
+		}
+	}
+	// -b takes a cookie file or "name=value" pairs like ours.
+	if Cookie != "" {
+		h.Del("Cookie")
+		add("-b", Cookie)
+	}
+	if Compressed {
+		h.Del("Accept-Encoding")
+		add("--compressed")
+	}
+	// a multipart boundary is picked again by curl, and curl sends
+	// Expect: 100-continue for large bodies by itself.
+	if len(FormFields) > 0 {
+		h.Del("Content-Type")
+	}
+	// the body is shown uncompressed.
+	if CompressRequest {
+		h.Del("Content-Encoding")
+		omitted = append(omitted, "--compressed-request")
+	}
+	if len(Trailers) > 0 {
+		omitted = append(omitted, "--trailer")
+	}
+	if NoExpect100 {
+		add("-H", "Expect:")
+	}
+	h.Del("Expect")
+	if req.Host != "" && req.Host != req.URL.Host {
+		h.Set("Host", req.Host)
+	}
+	// -H values are shown as typed, with ${VAR} unexpanded, and other
+	// credentials redacted like under -vv, the command is for sharing.
+	for _, raw := range CustomHeaders {
+		if i := strings.Index(raw, ":"); i > 0 {
+			h.Del(strings.TrimSpace(raw[:i]))
+		}
+	}
+	for _, k := range headerNames(h) {
+		for _, v := range h[k] {
+			if !ShowSecrets {
+				v = redactHeader(k, v)
+			}
+			add("-H", k+": "+v)
+		}
+	}
+	for _, raw := range CustomHeaders {
+		raw = strings.TrimSpace(raw)
+		if NoExpand || !strings.Contains(raw, "$") {
+			add("-H", raw)
+			continue
+		}
+		// the shell expands ${VAR} as goURL would have.
+		add("-H", shellQuoteExpand(raw))
+		quoted[len(args)-1] = true
+	}
+
+	switch {
+	case len(FormFields) > 0:
+		for _, f := range FormFields {
+			add("-F", f)
+		}
+	case strings.HasPrefix(HttpData, "@") && !literalBody:
+		add("--data-binary", HttpData)
+	case HttpData != "":
+		add("--data-raw", HttpData)
+	}
+
+	if FollowRedirects {
+		add("-L")
+		if MaxRedirects != 50 {
+			add("--max-redirs", fmt.Sprint(MaxRedirects))
+		}
+	}
+	if InsecureSkip {
+		add("-k")
+	}
+	if CACert != "" {
+		add("--cacert", CACert)
+	}
+	switch {
+	case HTTP3:
+		add("--http3-only")
+	case HTTP11:
+		add("--http1.1")
+	}
+	if ProxyURL != "" {
+		add("-x", ProxyURL)
+	}
+	if UnixSocket != "" {
+		add("--unix-socket", UnixSocket)
+	}
+	switch {
+	case IPv4Only:
+		add("-4")
+	case IPv6Only:
+		add("-6")
+	}
+	if Interface != "" {
+		add("--interface", Interface)
+	}
+	if LocalPort != 0 {
+		add("--local-port", fmt.Sprint(LocalPort))
+	}
+	for _, r := range Resolve {
+		add("--resolve", r)
+	}
+	for _, c := range ConnectTo {
+		add("--connect-to", c)
+	}
+	if MaxTime > 0 {
+		add("-m", fmt.Sprint(MaxTime.Seconds()))
+	}
+	if ConnectTimeout != 30*time.Second {
+		add("--connect-timeout", fmt.Sprint(ConnectTimeout.Seconds()))
+	}
+	if OutputFile != "" {
+		add("-o", OutputFile)
+	}
+	target := req.URL.String()
+	if PathAsIs && req.URL.Opaque != "" {
+		add("--path-as-is")
+		target = req.URL.Scheme + "://" + req.URL.Host + strings.TrimPrefix(req.URL.Opaque, "//"+req.URL.Host)
+		if req.URL.RawQuery != "" {
+			target += "?" + req.URL.RawQuery
+		}
+	}
+	// curl would expand brackets and braces left in the URL.
+	if strings.ContainsAny(req.URL.RequestURI(), "[]{}") {
+		add("--globoff")
+	}
+	add(target)
+
+	for i := 1; i < len(args); i++ {
+		if !quoted[i] {
+			args[i] = shellQuote(args[i])
+		}
+	}
+	return strings.Join(args, " "), omitted
+}
+
+// quote s for a POSIX shell, unless it is safe as is.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:@=,+%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// quote s in double quotes for a POSIX shell, leaving $VAR and ${VAR}
+// to be expanded like os.ExpandEnv does.
+func shellQuoteExpand(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '$' && i+1 < len(s) && s[i+1] == '{':
+			if end := strings.IndexByte(s[i:], '}'); end > 0 {
+				b.WriteString(s[i : i+end+1])
+				i += end
+				continue
+			}
+			b.WriteString(`\$`)
+		case c == '$' && i+1 < len(s) && (s[i+1] == '_' || isAlpha(s[i+1])):
+			b.WriteByte(c)
+		case c == '$', c == '"', c == '\\', c == '`':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func isAlpha(c byte) bool { return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' }
@@ -0,0 +1,239 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// dial function of the transport.
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (f dialFunc) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return f(ctx, network, addr)
+}
+
+func (f dialFunc) Dial(network, addr string) (net.Conn, error) {
+	return f(context.Background(), network, addr)
+}
+
+// dial function for the transport.
+func newDialContext() (dialFunc, error) {
+	resolved, err := parseResolve(Resolve)
+	if err != nil {
+		return nil, err
+	}
+	connectTo, err := parseConnectTo(ConnectTo)
+	if err != nil {
+		return nil, err
+	}
+	// same keep-alive as http.DefaultTransport.
+	dialer := &net.Dialer{
+		Timeout:   ConnectTimeout,
+		KeepAlive: 30 * time.Second,
+	}
+	if DNSServer != "" {
+		dialer.Resolver = newResolver(DNSServer)
+	}
+	if Interface != "" || LocalPort != 0 {
+		if UnixSocket != "" {
+			return nil, errors.New(color.HiRedString("--interface and --local-port can't be used with --unix-socket"))
+		}
+		ip, err := localIP(Interface)
+		if err != nil {
+			return nil, err
+		}
+		dialer.LocalAddr = &net.TCPAddr{IP: ip, Port: LocalPort}
+	}
+	// --unix-socket ignores the URL host, which is still sent in
+	// the Host header.
+	if UnixSocket != "" {
+		return func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return recordHead(dialer.DialContext(ctx, "unix", UnixSocket))
+		}, nil
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		// -4/-6 never fall back to the other family.
+		switch {
+		case IPv4Only:
+			network = "tcp4"
+		case IPv6Only:
+			network = "tcp6"
+		}
+		// --connect-to may send us elsewhere, --resolve then applies
+		// to the new host. Both keep the Host header and TLS SNI of
+		// the URL host.
+		addr = connectTo.target(addr)
+		if ip, ok := resolved[addr]; ok {
+			_, port, _ := net.SplitHostPort(addr)
+			addr = net.JoinHostPort(ip, port)
+		}
+		return recordHead(dialer.DialContext(ctx, network, addr))
+	}, nil
+}
+
+// wrap c to record response header order with --no-sort-headers.
+func recordHead(c net.Conn, err error) (net.Conn, error) {
+	if err != nil || !NoSortHeaders {
+		return c, err
+	}
+	return &headConn{Conn: c}, nil
+}
+
+// parse --resolve HOST:PORT:ADDRESS entries into a "host:port" to address map.
+func parseResolve(entries []string) (map[string]string, error) {
+	resolved := make(map[string]string, len(entries))
+	for _, e := range entries {
+		// an IPv6 host is written in brackets, the address may be an
+		// IPv6 one without them.
+		parts := splitHostPorts(e)
+		if len(parts) < 3 {
+			return nil, errors.New(color.HiRedString("Bad --resolve %q, want HOST:PORT:ADDRESS", e))
+		}
+		host := strings.TrimSuffix(strings.TrimPrefix(parts[0], "["), "]")
+		if _, err := strconv.ParseUint(parts[1], 10, 16); err != nil {
+			return nil, errors.New(color.HiRedString("Bad --resolve %q, %q is not a port", e, parts[1]))
+		}
+		addr := strings.Join(parts[2:], ":")
+		ip := strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		if net.ParseIP(ip) == nil {
+			return nil, errors.New(color.HiRedString("Bad --resolve %q, %q is not an IP address", e, addr))
+		}
+		resolved[net.JoinHostPort(host, parts[1])] = ip
+	}
+	return resolved, nil
+}
+
+// one --connect-to HOST1:PORT1:HOST2:PORT2 rule, empty fields match any
+// host or port, or keep the original one.
+type connectRule struct {
+	host, port     string
+	toHost, toPort string
+}
+
+type connectRules []connectRule
+
+// address to dial instead of addr, the first matching rule wins.
+func (rules connectRules) target(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	for _, r := range rules {
+		if (r.host != "" && r.host != host) || (r.port != "" && r.port != port) {
+			continue
+		}
+		if r.toHost != "" {
+			host = r.toHost
+		}
+		if r.toPort != "" {
+			port = r.toPort
+		}
+		return net.JoinHostPort(host, port)
+	}
+	return addr
+}
+
+// parse --connect-to entries, IPv6 hosts are written in brackets.
+func parseConnectTo(entries []string) (connectRules, error) {
+	rules := make(connectRules, 0, len(entries))
+	for _, e := range entries {
+		parts := splitHostPorts(e)
+		if len(parts) != 4 {
+			return nil, errors.New(color.HiRedString("Bad --connect-to %q, want HOST1:PORT1:HOST2:PORT2", e))
+		}
+		for i := range parts {
+			parts[i] = strings.TrimSuffix(strings.TrimPrefix(parts[i], "["), "]")
+		}
+		for _, port := range []string{parts[1], parts[3]} {
+			if _, err := strconv.ParseUint(port, 10, 16); port != "" && err != nil {
+				return nil, errors.New(color.HiRedString("Bad --connect-to %q, %q is not a port", e, port))
+			}
+		}
+		rules = append(rules, connectRule{host: parts[0], port: parts[1], toHost: parts[2], toPort: parts[3]})
+	}
+	return rules, nil
+}
+
+// split s at colons outside of brackets.
+func splitHostPorts(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case ':':
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// source address for --interface, an IP or the name of an interface.
+// An empty name means any address.
+func localIP(name string) (net.IP, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if ip := net.ParseIP(name); ip != nil {
+		return ip, nil
+	}
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, errors.New(color.HiRedString("No interface %q, have %s", name, interfaceNames()))
+	}
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, errors.New(color.HiRedString("Unable to read addresses of %s: %v", name, err))
+	}
+	for _, a := range addrs {
+		ipNet, ok := a.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		// -6 wants an IPv6 source, otherwise prefer IPv4.
+		if (ipNet.IP.To4() == nil) == IPv6Only {
+			return ipNet.IP, nil
+		}
+	}
+	return nil, errors.New(color.HiRedString("Interface %s has no usable address", name))
+}
+
+// names of the local interfaces for error messages.
+func interfaceNames() string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "none"
+	}
+	names := make([]string, 0, len(ifaces))
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// resolver asking the DNS server at addr, port 53 unless given,
+// instead of the system one.
+func newResolver(addr string) *net.Resolver {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(strings.Trim(addr, "[]"), "53")
+	}
+	d := &net.Dialer{Timeout: 5 * time.Second}
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+			return d.DialContext(ctx, network, addr)
+		},
+	}
+}
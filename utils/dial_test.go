@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseResolve(t *testing.T) {
+	got, err := parseResolve([]string{
+		"example.com:443:127.0.0.1",
+		"v6.example.com:80:[::1]",
+		"other.example.com:80:2001:db8::1",
+		"[::1]:443:127.0.0.1",
+		"[2001:db8::2]:8443:[::1]",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{
+		"example.com:443":      "127.0.0.1",
+		"v6.example.com:80":    "::1",
+		"other.example.com:80": "2001:db8::1",
+		"[::1]:443":            "127.0.0.1",
+		"[2001:db8::2]:8443":   "::1",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwant %v", got, want)
+	}
+
+	for _, e := range []string{
+		"example.com:443",
+		"example.com:https:127.0.0.1",
+		"example.com:443:not-an-ip",
+		"::1:443:127.0.0.1",
+	} {
+		if _, err := parseResolve([]string{e}); err == nil {
+			t.Errorf("parseResolve(%q): no error", e)
+		}
+	}
+}
+
+func TestParseConnectTo(t *testing.T) {
+	rules, err := parseConnectTo([]string{"[::1]:443:example.com:8443", "::[2001:db8::1]:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ addr, want string }{
+		{"[::1]:443", "example.com:8443"},
+		{"other.com:80", "[2001:db8::1]:80"},
+	}
+	for _, tt := range tests {
+		if got := rules.target(tt.addr); got != tt.want {
+			t.Errorf("target(%q) = %q, want %q", tt.addr, got, tt.want)
+		}
+	}
+}
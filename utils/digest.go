@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// --sha256 and --md5, given as --sha256 HEX to check the digest or
+// with an empty value, --sha256=, to print it. A bare "sha256" line of
+// the config file prints it too.
+type digestFlag struct {
+	on   bool
+	want string
+}
+
+func (d *digestFlag) String() string { return d.want }
+
+func (d *digestFlag) Set(v string) error {
+	d.on = true
+	if v == "" || v == "true" {
+		d.want = ""
+		return nil
+	}
+	if _, err := hex.DecodeString(v); err != nil {
+		return errors.New("want a hex digest")
+	}
+	d.want = strings.ToLower(v)
+	return nil
+}
+
+// digest computed while the body is read.
+type bodyDigest struct {
+	name string
+	flag *digestFlag
+	h    hash.Hash
+}
+
+// hash resp.Body as it is read for each digest flag given.
+func hashBody(resp *http.Response) []*bodyDigest {
+	var digests []*bodyDigest
+	if SHA256.on {
+		digests = append(digests, &bodyDigest{"SHA-256", &SHA256, sha256.New()})
+	}
+	if MD5.on {
+		digests = append(digests, &bodyDigest{"MD5", &MD5, md5.New()})
+	}
+	if len(digests) == 0 {
+		return nil
+	}
+	hashes := make([]io.Writer, len(digests))
+	for i, d := range digests {
+		hashes[i] = d.h
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(resp.Body, io.MultiWriter(hashes...)), resp.Body}
+	return digests
+}
+
+// print each digest, or compare it with the expected one.
+func checkDigests(w io.Writer, digests []*bodyDigest) error {
+	for _, d := range digests {
+		got := hex.EncodeToString(d.h.Sum(nil))
+		if d.flag.want == "" {
+			fprintf(w, "%s %s\n", grayscale(14)(d.name+":"), color.CyanString(got))
+			continue
+		}
+		if got != d.flag.want {
+			return errors.New(color.HiRedString("%s mismatch: got %s, want %s", d.name, got, d.flag.want))
+		}
+		bannerf(w, "%s %s\n", grayscale(14)(d.name+":"), color.GreenString("%s OK", got))
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// parse a "Digest k=v, k="v"" challenge of a WWW-Authenticate header,
+// or report that it isn't one.
+func parseDigestChallenge(h string) (map[string]string, bool) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(h), " ")
+	if !strings.EqualFold(scheme, "Digest") {
+		return nil, false
+	}
+	params := make(map[string]string)
+	for rest = strings.TrimSpace(rest); rest != ""; {
+		eq := strings.IndexByte(rest, '=')
+		if eq < 0 {
+			break
+		}
+		key := strings.ToLower(strings.TrimSpace(rest[:eq]))
+		rest = strings.TrimSpace(rest[eq+1:])
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			// a quoted value may hold commas, \ escapes the next character.
+			var b strings.Builder
+			i := 1
+			for ; i < len(rest) && rest[i] != '"'; i++ {
+				if rest[i] == '\\' && i+1 < len(rest) {
+					i++
+				}
+				b.WriteByte(rest[i])
+			}
+			value, rest = b.String(), rest[min(i+1, len(rest)):]
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+			value = strings.TrimSpace(value)
+		}
+		params[key] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return params, true
+}
+
+// Authorization header answering the digest challenge of resp, a 401
+// to req, with the -u credentials. It is empty when resp has no digest
+// challenge this can answer.
+func digestAuthorization(req *http.Request, resp *http.Response) (string, error) {
+	var ch map[string]string
+	for _, h := range resp.Header.Values("WWW-Authenticate") {
+		if c, ok := parseDigestChallenge(h); ok {
+			ch = c
+			break
+		}
+	}
+	if ch == nil {
+		return "", nil
+	}
+	user, pass, err := basicAuth()
+	if err != nil {
+		return "", err
+	}
+	cnonce, err := digestNonce()
+	if err != nil {
+		return "", err
+	}
+	return digestResponse(ch, req.Method, req.URL.RequestURI(), user, pass, cnonce)
+}
+
+// Authorization header of a request to uri answering the digest
+// challenge ch, with the client nonce cnonce.
+func digestResponse(ch map[string]string, method, uri, user, pass, cnonce string) (string, error) {
+	algorithm := ch["algorithm"]
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "", "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return "", errors.New(color.HiRedString("Unsupported digest algorithm %q", algorithm))
+	}
+	h := func(s string) string {
+		d := newHash()
+		d.Write([]byte(s))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+	// only qop=auth, auth-int would need a hash of the body.
+	qop := ""
+	if ch["qop"] != "" {
+		for _, q := range strings.Split(ch["qop"], ",") {
+			if strings.TrimSpace(q) == "auth" {
+				qop = "auth"
+			}
+		}
+		if qop == "" {
+			return "", errors.New(color.HiRedString("Unsupported digest qop %q", ch["qop"]))
+		}
+	}
+
+	realm, nonce := ch["realm"], ch["nonce"]
+	const nc = "00000001"
+	ha1 := h(user + ":" + realm + ":" + pass)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + nonce + ":" + cnonce)
+	}
+	ha2 := h(method + ":" + uri)
+	response := h(ha1 + ":" + nonce + ":" + ha2)
+	if qop != "" {
+		response = h(strings.Join([]string{ha1, nonce, nc, cnonce, qop, ha2}, ":"))
+	}
+
+	auth := fmt.Sprintf(`Digest username=%q, realm=%q, nonce=%q, uri=%q, response=%q`, user, realm, nonce, uri, response)
+	if algorithm != "" {
+		auth += ", algorithm=" + algorithm
+	}
+	if qop != "" {
+		auth += fmt.Sprintf(`, qop=%s, nc=%s, cnonce=%q`, qop, nc, cnonce)
+	}
+	if opaque, ok := ch["opaque"]; ok {
+		auth += fmt.Sprintf(`, opaque=%q`, opaque)
+	}
+	return auth, nil
+}
+
+// random client nonce.
+func digestNonce() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", errors.New(color.HiRedString("Unable to create digest nonce: %v", err))
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+// examples of RFC 7616 section 3.9.1 and RFC 2617 section 3.5.
+func TestDigestResponse(t *testing.T) {
+	const rfc7616 = `Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=%s, ` +
+		`nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`
+	tests := []struct {
+		name      string
+		challenge string
+		user      string
+		pass      string
+		cnonce    string
+		response  string
+	}{
+		{"RFC 7616 MD5", strings.Replace(rfc7616, "%s", "MD5", 1), "Mufasa", "Circle of Life",
+			"f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"RFC 7616 SHA-256", strings.Replace(rfc7616, "%s", "SHA-256", 1), "Mufasa", "Circle of Life",
+			"f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+		{"RFC 2617", `Digest realm="testrealm@host.com", qop="auth,auth-int", nonce="dcd98b7102dd2f0e8b11d0f600bfb0c093", ` +
+			`opaque="5ccc069c403ebaf9f0171e9517f40e41"`, "Mufasa", "Circle Of Life",
+			"0a4f113b", "6629fae49393a05397450978507c4ef1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ch, ok := parseDigestChallenge(tt.challenge)
+			if !ok {
+				t.Fatalf("parseDigestChallenge(%q) found no challenge", tt.challenge)
+			}
+			auth, err := digestResponse(ch, "GET", "/dir/index.html", tt.user, tt.pass, tt.cnonce)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(auth, `response="`+tt.response+`"`) {
+				t.Errorf("got %s\nwant response=%q", auth, tt.response)
+			}
+			for _, p := range []string{`opaque="` + ch["opaque"] + `"`, "qop=auth,", `nc=00000001`, `cnonce="` + tt.cnonce + `"`} {
+				if !strings.Contains(auth, p) {
+					t.Errorf("got %s\nwant %s", auth, p)
+				}
+			}
+		})
+	}
+}
+
+func TestParseDigestChallenge(t *testing.T) {
+	ch, ok := parseDigestChallenge(`Digest realm="a, \"b\"", nonce=xyz,stale=false`)
+	if !ok {
+		t.Fatal("no challenge found")
+	}
+	for k, want := range map[string]string{"realm": `a, "b"`, "nonce": "xyz", "stale": "false"} {
+		if ch[k] != want {
+			t.Errorf("%s = %q, want %q", k, ch[k], want)
+		}
+	}
+	if _, ok := parseDigestChallenge(`Basic realm="x"`); ok {
+		t.Error("Basic challenge taken for a digest one")
+	}
+}
+
+func TestDigestResponseUnsupported(t *testing.T) {
+	for _, c := range []string{`Digest nonce="n", algorithm=SHA-512-256`, `Digest nonce="n", qop="auth-int"`} {
+		ch, _ := parseDigestChallenge(c)
+		if _, err := digestResponse(ch, "GET", "/", "u", "p", "c"); err == nil {
+			t.Errorf("%s: no error", c)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package utils
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/fatih/color"
+)
+
+// encodings asked for by --compressed.
+const acceptEncoding = "gzip, deflate, br"
+
+// reader counting the bytes read through it.
+type countingReader struct {
+	io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.Reader.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// report whether resp comes with a body to read: not for HEAD, 1xx,
+// 204 and 304, nor when Content-Length is 0.
+func hasBody(resp *http.Response) bool {
+	switch {
+	case resp.Request != nil && resp.Request.Method == http.MethodHead:
+		return false
+	case resp.StatusCode < 200, resp.StatusCode == http.StatusNoContent, resp.StatusCode == http.StatusNotModified:
+		return false
+	}
+	return resp.ContentLength != 0
+}
+
+// replace resp.Body with a counting reader over it.
+func countBody(resp *http.Response) *countingReader {
+	counter := &countingReader{Reader: resp.Body}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{counter, resp.Body}
+	return counter
+}
+
+// decompress resp.Body according to its Content-Encoding. The returned
+// readers count compressed and decompressed bytes, both are nil when
+// the body isn't encoded, e.g. the server ignored Accept-Encoding.
+func decodeBody(resp *http.Response) (raw, decoded *countingReader, err error) {
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "" || encoding == "identity" {
+		return nil, nil, nil
+	}
+
+	raw = &countingReader{Reader: resp.Body}
+	var r io.Reader
+	switch encoding {
+	case "gzip", "x-gzip":
+		r, err = gzip.NewReader(raw)
+	case "deflate":
+		r, err = newDeflateReader(raw)
+	case "br":
+		r = brotli.NewReader(raw)
+	default:
+		return nil, nil, errors.New(color.HiRedString("Unsupported Content-Encoding %q", encoding))
+	}
+	// some servers label an empty body too.
+	if err == io.EOF && raw.n == 0 {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, errors.New(color.HiRedString("Unable to decode %s body: %v", encoding, err))
+	}
+
+	decoded = &countingReader{Reader: r}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{decoded, resp.Body}
+	return raw, decoded, nil
+}
+
+// "deflate" should be zlib-wrapped, but some servers send raw deflate.
+func newDeflateReader(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	// https://datatracker.ietf.org/doc/html/rfc1950#section-2.2
+	if head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0 {
+		return zlib.NewReader(br)
+	}
+	return flate.NewReader(br), nil
+}
+
+// gzip r while it is read, for --compressed-request.
+func gzipBody(r io.Reader) io.Reader {
+	pr, pw := io.Pipe()
+	go func() {
+		zw := gzip.NewWriter(pw)
+		_, err := io.Copy(zw, r)
+		if err == nil {
+			err = zw.Close()
+		}
+		if c, ok := r.(io.Closer); ok {
+			c.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+	return pr
+}
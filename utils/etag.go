@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// validators kept by --etag-save, one "Name: value" line each.
+var validatorHeaders = []string{"ETag", "Last-Modified"}
+
+// save the ETag and Last-Modified of resp to name. A 304 keeps the
+// saved validators, they are still the current ones.
+func saveValidators(name string, resp *http.Response) error {
+	if resp.StatusCode == http.StatusNotModified {
+		return nil
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return errors.New(color.HiRedString("Unable to write ETag file: %v", err))
+	}
+	defer f.Close()
+	for _, k := range validatorHeaders {
+		if v := resp.Header.Get(k); v != "" {
+			if _, err := fmt.Fprintf(f, "%s: %s\n", k, v); err != nil {
+				return errors.New(color.HiRedString("Unable to write ETag file: %v", err))
+			}
+		}
+	}
+	return nil
+}
+
+// add If-None-Match and If-Modified-Since from a file of saveValidators.
+// A missing file sends an unconditional request, like curl.
+func addConditions(req *http.Request, name string) error {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return errors.New(color.HiRedString("Unable to read ETag file: %v", err))
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.SplitN(scanner.Text(), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		v := strings.TrimSpace(parts[1])
+		switch http.CanonicalHeaderKey(strings.TrimSpace(parts[0])) {
+		case "Etag":
+			req.Header.Set("If-None-Match", v)
+		case "Last-Modified":
+			req.Header.Set("If-Modified-Since", v)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.New(color.HiRedString("Unable to read ETag file: %v", err))
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+const (
+	formContentType = "application/x-www-form-urlencoded"
+	jsonContentType = "application/json"
+)
+
+// a body of key=value pairs joined with &.
+var formPattern = regexp.MustCompile(`^[^=&\s]+=[^&\s]*(&[^=&\s]+=[^&\s]*)*$`)
+
+// Content-Type of a -d body without -H Content-Type, guessed from its
+// first bytes: binary, JSON, a form, or else text/plain.
+func guessContentType(head []byte) string {
+	s := bytes.TrimSpace(head)
+	switch {
+	case !strings.HasPrefix(http.DetectContentType(head), "text/"):
+		return "application/octet-stream"
+	case len(s) > 0 && (s[0] == '{' || s[0] == '['):
+		return jsonContentType
+	case formPattern.Match(s):
+		return formContentType
+	}
+	return "text/plain"
+}
+
+// guess the Content-Type of body by peeking at it. A file is read at
+// offset 0, stdin through a buffer keeping the peeked bytes.
+func sniffBody(body io.Reader) (io.Reader, string, error) {
+	head := make([]byte, 512)
+	if ra, ok := body.(io.ReaderAt); ok {
+		if n, err := ra.ReadAt(head, 0); err == nil || err == io.EOF {
+			return body, guessContentType(head[:n]), nil
+		}
+	}
+	br := bufio.NewReaderSize(body, len(head))
+	peeked, err := br.Peek(len(head))
+	if err != nil && err != io.EOF {
+		return nil, "", errors.New(color.HiRedString("Unable to read body: %v", err))
+	}
+	r := io.Reader(br)
+	// net/http closes the body, which still has to close the file.
+	if c, ok := body.(io.Closer); ok {
+		r = struct {
+			io.Reader
+			io.Closer
+		}{br, c}
+	}
+	return r, guessContentType(peeked), nil
+}
+
+// how a -d style flag adds its value to the body.
+type DataKind int
+
+const (
+	DataPlain      DataKind = iota // -d, "@file" reads a file
+	DataRaw                        // --data-raw, taken as is
+	DataURLEncoded                 // --data-urlencode
+)
+
+// one -d, --data-raw or --data-urlencode value.
+type dataPart struct {
+	kind  DataKind
+	value string
+}
+
+var (
+	// the -d style values in command line order.
+	dataParts []dataPart
+
+	// the -d body is text, even when it starts with "@".
+	literalBody bool
+)
+
+type dataFlag DataKind
+
+func (d dataFlag) String() string { return "" }
+
+func (d dataFlag) Set(v string) error {
+	dataParts = append(dataParts, dataPart{kind: DataKind(d), value: v})
+	if DataKind(d) == DataURLEncoded {
+		DataURLEncode = append(DataURLEncode, v)
+	} else {
+		HttpData = v
+	}
+	return nil
+}
+
+// DataFlag returns the flag.Value of a -d style flag. All of them add
+// to the same body, joined with "&".
+func DataFlag(kind DataKind) flag.Value {
+	return dataFlag(kind)
+}
+
+// turn --json and the -d style values into the -d body, once for all
+// requests. A single -d is left alone, it may stream a file or stdin.
+func prepareBody() error {
+	if JSONData != "" && (HttpData != "" || len(DataURLEncode) > 0 || len(FormFields) > 0) {
+		return errors.New(color.HiRedString("--json can't be used together with -d, --data-urlencode or -F"))
+	}
+	if len(FormFields) > 0 && (HttpData != "" || len(DataURLEncode) > 0) {
+		return errors.New(color.HiRedString("-F can't be used together with -d or --data-urlencode"))
+	}
+	var err error
+	switch {
+	case JSONData != "":
+		HttpData, err = jsonBody(JSONData)
+	case len(dataParts) > 1 || (len(dataParts) == 1 && dataParts[0].kind != DataPlain):
+		HttpData, err = joinData(dataParts)
+		literalBody = true
+	}
+	return err
+}
+
+// read a --json body, which may be @file, and check it is valid JSON.
+func jsonBody(data string) (string, error) {
+	r, _, err := createBody(data)
+	if err != nil {
+		return "", err
+	}
+	s, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", errors.New(color.HiRedString("Unable to read body: %v", err))
+	}
+	if !json.Valid(s) {
+		return "", errors.New(color.HiRedString("Invalid JSON in --json: %s", jsonSyntaxError(s)))
+	}
+	return string(s), nil
+}
+
+// describe why body isn't valid JSON.
+func jsonSyntaxError(body []byte) string {
+	var v interface{}
+	err := json.Unmarshal(body, &v)
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return fmt.Sprintf("%v at offset %d", syntaxErr, syntaxErr.Offset)
+	}
+	if err != nil {
+		return err.Error()
+	}
+	return "not a JSON value"
+}
+
+// join the -d style values into one body, in the order given.
+func joinData(data []dataPart) (string, error) {
+	parts := make([]string, 0, len(data))
+	for _, d := range data {
+		switch {
+		case d.kind == DataURLEncoded:
+			part, err := urlencodePair(d.value)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, part)
+		case d.kind == DataPlain && strings.HasPrefix(d.value, "@"):
+			// -d @file has to be read to join it with the others.
+			r, _, err := createBody(d.value)
+			if err != nil {
+				return "", err
+			}
+			s, err := ioutil.ReadAll(r)
+			if c, ok := r.(io.Closer); ok {
+				c.Close()
+			}
+			if err != nil {
+				return "", errors.New(color.HiRedString("Unable to read body: %v", err))
+			}
+			parts = append(parts, string(s))
+		default:
+			parts = append(parts, d.value)
+		}
+	}
+	return strings.Join(parts, "&"), nil
+}
+
+// encode one --data-urlencode value like curl: "content", "=content",
+// "name=content", "@file" and "name@file". Only the content is encoded.
+func urlencodePair(pair string) (string, error) {
+	eq := strings.Index(pair, "=")
+	at := strings.Index(pair, "@")
+	switch {
+	case eq >= 0 && (at < 0 || eq < at):
+		name, content := pair[:eq], pair[eq+1:]
+		if name == "" {
+			return url.QueryEscape(content), nil
+		}
+		return name + "=" + url.QueryEscape(content), nil
+	case at >= 0:
+		name := pair[:at]
+		content, err := ioutil.ReadFile(pair[at+1:])
+		if err != nil {
+			return "", errors.New(color.HiRedString("Unable to read --data-urlencode file: %v", err))
+		}
+		if name == "" {
+			return url.QueryEscape(string(content)), nil
+		}
+		return name + "=" + url.QueryEscape(string(content)), nil
+	}
+	return url.QueryEscape(pair), nil
+}
+
+// stream -F fields as a multipart/form-data body, "name=value" or
+// "name=@file". The returned content type carries the boundary.
+func multipartBody(fields []string) (io.Reader, string, error) {
+	for _, field := range fields {
+		if !strings.Contains(field, "=") {
+			return nil, "", errors.New(color.HiRedString("Bad form field %q, want \"name=value\" or \"name=@file\"", field))
+		}
+		// report a missing file before the request is sent.
+		if value := strings.SplitN(field, "=", 2)[1]; strings.HasPrefix(value, "@") {
+			if _, err := os.Stat(value[1:]); err != nil {
+				return nil, "", errors.New(color.HiRedString("Unable to read form file: %v", err))
+			}
+		}
+	}
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeParts(mw, fields))
+	}()
+	return pr, mw.FormDataContentType(), nil
+}
+
+// write every field, then the closing boundary.
+func writeParts(mw *multipart.Writer, fields []string) error {
+	for _, field := range fields {
+		parts := strings.SplitN(field, "=", 2)
+		name, value := parts[0], parts[1]
+		if !strings.HasPrefix(value, "@") {
+			if err := mw.WriteField(name, value); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := writeFilePart(mw, name, value[1:]); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// copy a file into a part, with its Content-Type guessed from the extension.
+func writeFilePart(mw *multipart.Writer, name, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("Unable to read form file: %w", err)
+	}
+	defer f.Close()
+	contentType := mime.TypeByExtension(filepath.Ext(file))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	h := make(textproto.MIMEHeader)
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(name), quoteEscaper.Replace(filepath.Base(file))))
+	h.Set("Content-Type", contentType)
+	part, err := mw.CreatePart(h)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, f)
+	return err
+}
@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"net/textproto"
+	"sync"
+)
+
+// header names of a response in the order the server sent them, for
+// --no-sort-headers.
+type headerOrder struct {
+	mu    sync.Mutex
+	names []string
+}
+
+func (o *headerOrder) set(names []string) {
+	o.mu.Lock()
+	o.names = names
+	o.mu.Unlock()
+}
+
+func (o *headerOrder) get() []string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.names
+}
+
+type headerOrderKey struct{}
+
+// connection recording the header names of the next response head read
+// from it. It sees plain HTTP/1.x only, under TLS the bytes are encrypted.
+type headConn struct {
+	net.Conn
+
+	mu    sync.Mutex
+	order *headerOrder // nil when not recording
+	head  []byte
+}
+
+// record the head of the response to the request about to be sent.
+func (c *headConn) expect(order *headerOrder) {
+	c.mu.Lock()
+	c.order = order
+	c.head = c.head[:0]
+	c.mu.Unlock()
+}
+
+func (c *headConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.order == nil || n == 0 {
+		return n, err
+	}
+	c.head = append(c.head, p[:n]...)
+	for {
+		end := bytes.Index(c.head, []byte("\r\n\r\n"))
+		if end < 0 {
+			// give up on a head this large, net/http refuses it anyway.
+			if len(c.head) > http.DefaultMaxHeaderBytes {
+				c.order = nil
+			}
+			return n, err
+		}
+		lines := bytes.Split(c.head[:end], []byte("\r\n"))
+		// skip 1xx interim responses, the final one follows.
+		if status := bytes.Fields(lines[0]); len(status) > 1 && len(status[1]) == 3 && status[1][0] == '1' {
+			c.head = c.head[end+4:]
+			continue
+		}
+		names := make([]string, 0, len(lines)-1)
+		seen := make(map[string]bool, len(lines)-1)
+		for _, line := range lines[1:] {
+			i := bytes.IndexByte(line, ':')
+			if i <= 0 {
+				continue
+			}
+			k := textproto.CanonicalMIMEHeaderKey(string(bytes.TrimSpace(line[:i])))
+			if !seen[k] {
+				seen[k] = true
+				names = append(names, k)
+			}
+		}
+		c.order.set(names)
+		c.order = nil
+		return n, err
+	}
+}
+
+// header names of resp in display order: sorted, or as received with
+// --no-sort-headers when the order is known. Names the order misses,
+// like those of other protocols, follow sorted.
+func responseHeaderNames(resp *http.Response) []string {
+	sorted := headerNames(resp.Header)
+	if !NoSortHeaders || resp.Request == nil {
+		return sorted
+	}
+	order, _ := resp.Request.Context().Value(headerOrderKey{}).(*headerOrder)
+	if order == nil {
+		return sorted
+	}
+	names := make([]string, 0, len(sorted))
+	seen := make(map[string]bool, len(sorted))
+	for _, k := range order.get() {
+		if _, ok := resp.Header[k]; ok && !seen[k] {
+			seen[k] = true
+			names = append(names, k)
+		}
+	}
+	for _, k := range sorted {
+		if !seen[k] {
+			names = append(names, k)
+		}
+	}
+	return names
+}
@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+
+	"github.com/fatih/color"
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// round tripper for --http3. There is no fallback to TCP, so a server
+// without HTTP/3 fails the request.
+func newHTTP3Transport(tlsConfig *tls.Config) (*http3.RoundTripper, error) {
+	dial, err := newQUICDial()
+	if err != nil {
+		return nil, err
+	}
+	return &http3.RoundTripper{
+		TLSClientConfig:    tlsConfig,
+		DisableCompression: Raw,
+		QuicConfig: &quic.Config{
+			HandshakeIdleTimeout: TLSTimeout,
+		},
+		Dial: dial,
+	}, nil
+}
+
+// QUIC dial function applying the same connection flags as the TCP one:
+// -4/-6, --connect-to, --resolve, --dns-server, --interface, --local-port
+// and --connect-timeout, which also covers the QUIC handshake.
+func newQUICDial() (func(context.Context, string, *tls.Config, *quic.Config) (quic.EarlyConnection, error), error) {
+	if UnixSocket != "" {
+		return nil, errors.New(color.HiRedString("--http3 can't be used with --unix-socket"))
+	}
+	resolved, err := parseResolve(Resolve)
+	if err != nil {
+		return nil, err
+	}
+	connectTo, err := parseConnectTo(ConnectTo)
+	if err != nil {
+		return nil, err
+	}
+	local, err := localIP(Interface)
+	if err != nil {
+		return nil, err
+	}
+	resolver := net.DefaultResolver
+	if DNSServer != "" {
+		resolver = newResolver(DNSServer)
+	}
+	network, family := "udp", "ip"
+	switch {
+	case IPv4Only:
+		network, family = "udp4", "ip4"
+	case IPv6Only:
+		network, family = "udp6", "ip6"
+	}
+
+	dial := func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+		addr = connectTo.target(addr)
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+		if ip, ok := resolved[addr]; ok {
+			host = ip
+		}
+		ips, err := resolver.LookupIP(ctx, family, host)
+		if err != nil {
+			return nil, err
+		}
+		portNum, err := net.LookupPort(network, port)
+		if err != nil {
+			return nil, err
+		}
+		conn, err := net.ListenUDP(network, &net.UDPAddr{IP: local, Port: LocalPort})
+		if err != nil {
+			return nil, err
+		}
+		qc, err := quic.DialEarly(ctx, conn, &net.UDPAddr{IP: ips[0], Port: portNum}, tlsCfg, cfg)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		// quic-go leaves a socket it didn't open to the caller.
+		go func() {
+			<-qc.Context().Done()
+			conn.Close()
+		}()
+		return qc, nil
+	}
+	return func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+		if ConnectTimeout <= 0 {
+			return dial(ctx, addr, tlsCfg, cfg)
+		}
+		dialCtx, cancel := context.WithTimeout(ctx, ConnectTimeout)
+		defer cancel()
+		qc, err := dial(dialCtx, addr, tlsCfg, cfg)
+		// tell --connect-timeout apart from the request running out.
+		if err != nil && ctx.Err() == nil && dialCtx.Err() != nil {
+			return nil, &quicConnectTimeout{err}
+		}
+		return qc, err
+	}, nil
+}
+
+// --connect-timeout passing before a QUIC connection was up.
+type quicConnectTimeout struct{ err error }
+
+func (e *quicConnectTimeout) Error() string { return e.err.Error() }
+func (e *quicConnectTimeout) Unwrap() error { return e.err }
+
+// report whether err means the server never answered over QUIC.
+func isQUICTimeout(err error) bool {
+	var idle *quic.IdleTimeoutError
+	var handshake *quic.HandshakeTimeoutError
+	return errors.As(err, &idle) || errors.As(err, &handshake)
+}
+
+// friendlier error for a request sent with --http3.
+func http3Error(err error) error {
+	var connect *quicConnectTimeout
+	if errors.As(err, &connect) {
+		return errors.New(color.HiRedString("Connection timed out after %v: %v", ConnectTimeout, connect.err))
+	}
+	if isQUICTimeout(err) {
+		return errors.New(color.HiRedString("Server doesn't support HTTP/3: no QUIC answer (%v)", err))
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// report whether the response declares a JSON body.
+func isJSON(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// indent and color a JSON body, keeping the key order.
+// ok is false when body isn't valid JSON.
+func prettyJSON(body []byte) (s string, ok bool) {
+	if !json.Valid(body) {
+		return "", false
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	tok, err := dec.Token()
+	if err != nil {
+		return "", false
+	}
+	var b strings.Builder
+	if err := writeJSON(&b, dec, tok, ""); err != nil {
+		return "", false
+	}
+	return b.String(), true
+}
+
+// write one JSON value starting with tok.
+func writeJSON(b *strings.Builder, dec *json.Decoder, tok json.Token, indent string) error {
+	switch v := tok.(type) {
+	case json.Delim:
+		b.WriteString(string(v))
+		empty := true
+		for dec.More() {
+			if !empty {
+				b.WriteString(",")
+			}
+			empty = false
+			b.WriteString("\n" + indent + "  ")
+			if v == '{' {
+				key, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				b.WriteString(color.BlueString("%s", quoteJSON(key.(string))) + ": ")
+			}
+			next, err := dec.Token()
+			if err != nil {
+				return err
+			}
+			if err := writeJSON(b, dec, next, indent+"  "); err != nil {
+				return err
+			}
+		}
+		end, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if !empty {
+			b.WriteString("\n" + indent)
+		}
+		b.WriteString(end.(json.Delim).String())
+	case string:
+		b.WriteString(color.GreenString("%s", quoteJSON(v)))
+	case json.Number:
+		b.WriteString(color.CyanString("%s", v))
+	case bool:
+		b.WriteString(color.YellowString("%t", v))
+	case nil:
+		b.WriteString(color.MagentaString("null"))
+	}
+	return nil
+}
+
+// quote a string the way JSON does, without escaping HTML.
+func quoteJSON(s string) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+// response head written by --head-json.
+type headJSON struct {
+	Status     int                 `json:"status"`
+	StatusText string              `json:"status_text"`
+	Proto      string              `json:"proto"`
+	TLS        string              `json:"tls,omitempty"`
+	Headers    map[string][]string `json:"headers"`
+}
+
+// write the status line and headers of resp as one JSON object.
+func writeHeadJSON(w io.Writer, resp *http.Response) error {
+	head := headJSON{
+		Status:     resp.StatusCode,
+		StatusText: strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode)+" "),
+		Proto:      resp.Proto,
+		Headers:    resp.Header,
+	}
+	if resp.TLS != nil {
+		head.TLS = tlsVersionName(resp.TLS.Version)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(head)
+}
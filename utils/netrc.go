@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// one machine or default entry of a .netrc file.
+type netrcEntry struct {
+	machine         string // empty for default
+	login, password string
+}
+
+type netrcEntries []netrcEntry
+
+// credentials for host, from its machine entry or else the default one.
+func (entries netrcEntries) lookup(host string) (netrcEntry, bool) {
+	for _, e := range entries {
+		if e.machine != "" && strings.EqualFold(e.machine, host) {
+			return e, true
+		}
+	}
+	for _, e := range entries {
+		if e.machine == "" {
+			return e, true
+		}
+	}
+	return netrcEntry{}, false
+}
+
+// .netrc entries for --netrc and --netrc-file, loaded by VisitURLs.
+var netrc netrcEntries
+
+// read the --netrc-file, or ~/.netrc with --netrc. A missing or malformed
+// file is only warned about on w, the requests go without credentials
+// or with the entries read before the error.
+func loadNetrc(w io.Writer) netrcEntries {
+	name := NetrcFile
+	if name == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			bannerf(w, "%s\n", color.YellowString("Warning: no home directory for .netrc: %v", err))
+			return nil
+		}
+		name = filepath.Join(home, ".netrc")
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		bannerf(w, "%s\n", color.YellowString("Warning: unable to read %s: %v", name, err))
+		return nil
+	}
+	entries, err := parseNetrc(string(data))
+	if err != nil {
+		bannerf(w, "%s\n", color.YellowString("Warning: %s: %v", name, err))
+	}
+	return entries
+}
+
+// parse the machine, default, login and password tokens of a .netrc.
+// account is skipped, and so are macdef definitions up to a blank line.
+func parseNetrc(data string) (netrcEntries, error) {
+	var entries netrcEntries
+	var cur *netrcEntry
+	lines := strings.Split(data, "\n")
+	for i := 0; i < len(lines); i++ {
+		// comments run to the end of the line.
+		line := lines[i]
+		if j := strings.Index(line, "#"); j >= 0 {
+			line = line[:j]
+		}
+		fields := strings.Fields(line)
+		for j := 0; j < len(fields); j++ {
+			tok := fields[j]
+			// every other token takes a value.
+			needValue := tok != "default" && tok != "macdef"
+			if needValue && j+1 >= len(fields) {
+				return entries, netrcError(i, "%q without a value", tok)
+			}
+			switch tok {
+			case "machine":
+				entries = append(entries, netrcEntry{machine: fields[j+1]})
+				cur = &entries[len(entries)-1]
+				j++
+			case "default":
+				entries = append(entries, netrcEntry{})
+				cur = &entries[len(entries)-1]
+			case "login", "password":
+				if cur == nil {
+					return entries, netrcError(i, "%q before any machine", tok)
+				}
+				if tok == "login" {
+					cur.login = fields[j+1]
+				} else {
+					cur.password = fields[j+1]
+				}
+				j++
+			case "account":
+				j++
+			case "macdef":
+				// the macro body ends at the next blank line.
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			default:
+				return entries, netrcError(i, "unknown token %q", tok)
+			}
+		}
+	}
+	return entries, nil
+}
+
+func netrcError(line int, format string, a ...interface{}) error {
+	return fmt.Errorf("line %d: %s", line+1, fmt.Sprintf(format, a...))
+}
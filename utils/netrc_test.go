@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNetrc(t *testing.T) {
+	data := `# comment
+machine example.com login alice password s3cret
+machine api.example.com
+	login bob # trailing comment
+	account acct
+	password pw
+
+macdef init
+cd /pub
+machine evil.example.com login mallory password x
+
+default login anon password guest@
+`
+	got, err := parseNetrc(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := netrcEntries{
+		{machine: "example.com", login: "alice", password: "s3cret"},
+		{machine: "api.example.com", login: "bob", password: "pw"},
+		{login: "anon", password: "guest@"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v\nwant %+v", got, want)
+	}
+
+	lookups := []struct {
+		host  string
+		login string
+	}{
+		{"example.com", "alice"},
+		{"EXAMPLE.com", "alice"},
+		{"api.example.com", "bob"},
+		{"evil.example.com", "anon"},
+		{"other.org", "anon"},
+	}
+	for _, l := range lookups {
+		e, ok := got.lookup(l.host)
+		if !ok || e.login != l.login {
+			t.Errorf("lookup(%q) = %+v, %v, want login %q", l.host, e, ok, l.login)
+		}
+	}
+	if _, ok := want[:2].lookup("other.org"); ok {
+		t.Error("lookup without a default entry found one")
+	}
+}
+
+func TestParseNetrcErrors(t *testing.T) {
+	for _, data := range []string{
+		"machine",
+		"login alice",
+		"machine a login",
+		"machine a user alice",
+	} {
+		if _, err := parseNetrc(data); err == nil {
+			t.Errorf("parseNetrc(%q): no error", data)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package utils
+
+import (
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// show the methods and CORS rules of an --options response, with the
+// CORS headers grouped together.
+func showOptions(w io.Writer, resp *http.Response) {
+	allow := resp.Header.Get("Allow")
+	if allow == "" {
+		allow = "(not sent)"
+	}
+	fprintf(w, "%s %s\n", grayscale(14)("Allow:"), color.CyanString(allow))
+
+	var cors []string
+	for _, k := range headerNames(resp.Header) {
+		if strings.HasPrefix(k, "Access-Control-") {
+			cors = append(cors, k)
+		}
+	}
+	if len(cors) == 0 {
+		fprintf(w, "%s %s\n", grayscale(14)("CORS:"), color.YellowString("no Access-Control-* headers, send an Origin header to get them"))
+		return
+	}
+	fprintf(w, "%s\n", grayscale(14)("CORS:"))
+	for _, k := range cors {
+		fprintf(w, "  %s %s\n", grayscale(14)(strings.TrimPrefix(k, "Access-Control-")+":"),
+			color.CyanString(strings.Join(resp.Header.Values(k), ", ")))
+	}
+}
@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+
+	"github.com/fatih/color"
+)
+
+// key of the output writer in a request context, for hooks like
+// CheckRedirect which only get the request.
+type outputKey struct{}
+
+// output writer of the request context, color.Output by default.
+func outputOf(ctx context.Context) io.Writer {
+	if w, ok := ctx.Value(outputKey{}).(io.Writer); ok {
+		return w
+	}
+	return color.Output
+}
+
+// visit urls with Parallel workers sharing client. Each URL's output
+// is buffered and printed at once, so outputs don't interleave.
+func visitParallel(client *http.Client, method string, urls []*url.URL) error {
+	jobs := make(chan *url.URL)
+	var (
+		mu     sync.Mutex
+		result = &failedURLs{total: len(urls)}
+		wg     sync.WaitGroup
+	)
+	for i := 0; i < Parallel && i < len(urls); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for u := range jobs {
+				var buf bytes.Buffer
+				bannerf(&buf, "\n%s %s\n", color.HiBlueString("=="), color.CyanString(u.String()))
+				err := visit(&buf, client, method, u)
+
+				mu.Lock()
+				_, _ = buf.WriteTo(color.Output)
+				if err != nil {
+					result.failed++
+					result.last = err
+					_, _ = fmt.Fprintln(os.Stderr, err)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, u := range urls {
+		jobs <- u
+	}
+	close(jobs)
+	wg.Wait()
+	if result.failed > 0 {
+		return result
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// width of the progress bar in characters.
+const progressWidth = 30
+
+// writer drawing download progress on out as bytes pass through it,
+// redrawn in place with carriage returns.
+type progress struct {
+	out   io.Writer
+	total int64 // -1 when unknown
+	n     int64
+	last  time.Time
+}
+
+func newProgress(out io.Writer, total int64) *progress {
+	return &progress{out: out, total: total}
+}
+
+func (p *progress) Write(b []byte) (int, error) {
+	p.n += int64(len(b))
+	// don't redraw for every small write.
+	if time.Since(p.last) >= 100*time.Millisecond {
+		p.draw()
+		p.last = time.Now()
+	}
+	return len(b), nil
+}
+
+// draw the final state and end the line.
+func (p *progress) finish() {
+	p.draw()
+	_, _ = fmt.Fprintln(p.out)
+}
+
+func (p *progress) draw() {
+	// without Content-Length only the running count is known.
+	if p.total <= 0 {
+		_, _ = fmt.Fprintf(p.out, "\r%s", formatBytes(p.n))
+		return
+	}
+	done := p.n * progressWidth / p.total
+	if done > progressWidth {
+		done = progressWidth
+	}
+	_, _ = fmt.Fprintf(p.out, "\r[%s%s] %3d%% %s/%s", strings.Repeat("#", int(done)), strings.Repeat(".", progressWidth-int(done)),
+		p.n*100/p.total, formatBytes(p.n), formatBytes(p.total))
+}
+
+// human readable byte count, e.g. "1.5 MB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,115 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/fatih/color"
+	"golang.org/x/net/proxy"
+)
+
+// set the transport proxy. -x overrides the proxy from the environment,
+// --noproxy hosts are always reached directly. A SOCKS5 proxy replaces
+// the transport dialer, which reaches the proxy with dial.
+func setProxy(tr *http.Transport, dial dialFunc) error {
+	// every connection goes to the socket.
+	if UnixSocket != "" {
+		if ProxyURL != "" {
+			return errors.New(color.HiRedString("--unix-socket can't be used with a proxy"))
+		}
+		tr.Proxy = nil
+		return nil
+	}
+	proxy := http.ProxyFromEnvironment
+	if ProxyURL != "" {
+		u, err := parseProxyURL(ProxyURL)
+		if err != nil {
+			return err
+		}
+		if u.Scheme == "socks5" || u.Scheme == "socks5h" {
+			tr.Proxy = nil
+			tr.DialContext = socksDialContext(u, dial)
+			return nil
+		}
+		proxy = http.ProxyURL(u)
+	}
+	tr.Proxy = proxy
+	if NoProxy != "" {
+		tr.Proxy = func(req *http.Request) (*url.URL, error) {
+			if noProxyMatch(req.URL.Hostname(), NoProxy) {
+				return nil, nil
+			}
+			return proxy(req)
+		}
+	}
+	return nil
+}
+
+// dial through the SOCKS5 proxy u, with dial used for the proxy itself
+// and for --noproxy hosts. The TLS handshake runs over the tunnel, so
+// it's still checked against the target host.
+func socksDialContext(u *url.URL, dial dialFunc) dialFunc {
+	var auth *proxy.Auth
+	if u.User != nil {
+		pass, _ := u.User.Password()
+		auth = &proxy.Auth{User: u.User.Username(), Password: pass}
+	}
+	// the error is only for an unknown network.
+	socks, _ := proxy.SOCKS5("tcp", u.Host, auth, dial)
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if host, _, err := net.SplitHostPort(addr); err == nil && NoProxy != "" && noProxyMatch(host, NoProxy) {
+			return dial(ctx, network, addr)
+		}
+		return socks.(proxy.ContextDialer).DialContext(ctx, network, addr)
+	}
+}
+
+// parse -x value, "host:port" means an http proxy like in curl.
+func parseProxyURL(s string) (*url.URL, error) {
+	if !strings.Contains(s, "://") {
+		s = "http://" + s
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, errors.New(color.HiRedString("Bad proxy URL %q: %v", s, err))
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, errors.New(color.HiRedString("Unsupported proxy scheme %q", u.Scheme))
+	}
+	if u.Hostname() == "" {
+		return nil, errors.New(color.HiRedString("Bad proxy URL %q: missing host", s))
+	}
+	return u, nil
+}
+
+// report whether host is in the comma separated --noproxy list.
+// "*" matches any host, a domain matches its subdomains too.
+func noProxyMatch(host, list string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry == "*" {
+			return true
+		}
+		if entry == "" {
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			if ip.Equal(net.ParseIP(host)) {
+				return true
+			}
+			continue
+		}
+		entry = strings.TrimPrefix(entry, ".")
+		if host == entry || strings.HasSuffix(host, "."+entry) {
+			return true
+		}
+	}
+	return false
+}
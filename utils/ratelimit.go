@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// reader holding reads down to rate bytes per second.
+type rateLimitedReader struct {
+	r     io.Reader
+	rate  int64
+	start time.Time
+	n     int64
+}
+
+func newRateLimitedReader(r io.Reader, rate int64) *rateLimitedReader {
+	return &rateLimitedReader{r: r, rate: rate, start: time.Now()}
+}
+
+func (l *rateLimitedReader) Read(p []byte) (int, error) {
+	// small reads keep the rate smooth.
+	if max := l.rate / 10; max > 0 && int64(len(p)) > max {
+		p = p[:max]
+	}
+	n, err := l.r.Read(p)
+	l.n += int64(n)
+	// sleep until the bytes so far fit the rate.
+	want := time.Duration(float64(l.n) / float64(l.rate) * float64(time.Second))
+	if wait := want - time.Since(l.start); wait > 0 {
+		time.Sleep(wait)
+	}
+	return n, err
+}
+
+// limit the rate resp.Body is read at.
+func limitBody(resp *http.Response, rate int64) {
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{newRateLimitedReader(resp.Body, rate), resp.Body}
+}
+
+// error reading a body larger than --max-filesize.
+var errMaxFileSize = errors.New("Maximum file size exceeded")
+
+// reader failing once more than max bytes are read.
+type maxSizeReader struct {
+	r   io.Reader
+	max int64
+	n   int64
+}
+
+func (m *maxSizeReader) Read(p []byte) (int, error) {
+	n, err := m.r.Read(p)
+	m.n += int64(n)
+	if m.n > m.max {
+		return n, fmt.Errorf("%w (%d bytes)", errMaxFileSize, m.max)
+	}
+	return n, err
+}
+
+// fail reading resp.Body beyond max bytes. A larger Content-Length
+// fails right away, before anything is downloaded.
+func limitBodySize(resp *http.Response, max int64) error {
+	if resp.ContentLength > max {
+		return errors.New(color.HiRedString("%v (%d bytes)", errMaxFileSize, max))
+	}
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{&maxSizeReader{r: resp.Body, max: max}, resp.Body}
+	return nil
+}
+
+// byte size flag like "100k", "1m" or "512", with 1024 based
+// k, m and g suffixes like curl.
+type byteSize int64
+
+func (b *byteSize) String() string { return strconv.FormatInt(int64(*b), 10) }
+
+func (b *byteSize) Set(s string) error {
+	mult := int64(1)
+	switch lower := strings.ToLower(s); {
+	case strings.HasSuffix(lower, "k"):
+		mult = 1 << 10
+	case strings.HasSuffix(lower, "m"):
+		mult = 1 << 20
+	case strings.HasSuffix(lower, "g"):
+		mult = 1 << 30
+	}
+	num := s
+	if mult > 1 {
+		num = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(num, 10, 64)
+	if err != nil || n <= 0 {
+		return errors.New("want a size like 100k, 1m or 512")
+	}
+	*b = byteSize(n * mult)
+	return nil
+}
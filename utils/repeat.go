@@ -0,0 +1,86 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// send the requests to urls Repeat times, or until Ctrl-C with -1,
+// printing one status line per request instead of the response.
+// The error is that of the last failed request of the last round, so
+// the exit code tells whether the endpoint is up at the end.
+func visitRepeat(w io.Writer, client *http.Client, method string, urls []*url.URL) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	// retries and redirects are only reported with -v.
+	logw := ioutil.Discard
+	if Verbose >= 1 {
+		logw = w
+	}
+
+	var last error
+	sent, failed := 0, 0
+	for round := 1; Repeat < 0 || round <= Repeat; round++ {
+		if round > 1 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(RepeatInterval):
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		last = nil
+		for _, u := range urls {
+			err := repeatOnce(ctx, w, logw, client, method, u, sent+1)
+			if ctx.Err() != nil {
+				break
+			}
+			sent++
+			if err != nil {
+				failed++
+				last = err
+			}
+		}
+	}
+	fprintf(w, "%s\n", grayscale(14)("%d requests, %d failed", sent, failed))
+	return last
+}
+
+// send one request of --repeat and print its status line, numbered n.
+func repeatOnce(ctx context.Context, w, logw io.Writer, client *http.Client, method string, u *url.URL, n int) error {
+	t := &timing{}
+	_, resp, err := doRequest(ctx, logw, client, method, u, newClientTrace(logw, t), t)
+	if err != nil {
+		if ctx.Err() == nil {
+			fprintf(w, "%s %s\n", grayscale(14)("#%d", n), err)
+		}
+		return err
+	}
+	size, err := io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	elapsed := time.Since(t.start).Round(time.Millisecond)
+	if err != nil {
+		err = bodyError("Unable to read response body: %v", err)
+		fprintf(w, "%s %s\n", grayscale(14)("#%d", n), err)
+		return err
+	}
+
+	status := color.GreenString(resp.Status)
+	if resp.StatusCode >= 400 {
+		status = color.HiRedString(resp.Status)
+	}
+	fprintf(w, "%s %s %s\n", grayscale(14)("#%d", n), status, grayscale(14)("%v, %d bytes, %s", elapsed, size, u))
+	if (FailOnError || ExitFromStatus) && resp.StatusCode >= 400 {
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package utils
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the whole transaction, written by --output-json.
+type report struct {
+	Request struct {
+		Method  string              `json:"method"`
+		URL     string              `json:"url"`
+		Headers map[string][]string `json:"headers"`
+	} `json:"request"`
+	Response struct {
+		Status     int                 `json:"status"`
+		StatusText string              `json:"status_text"`
+		Proto      string              `json:"proto"`
+		Headers    map[string][]string `json:"headers"`
+		BodyLength int64               `json:"body_length"`
+	} `json:"response"`
+	Timing   reportTiming `json:"timing"`
+	TLS      *reportTLS   `json:"tls,omitempty"`
+	RemoteIP string       `json:"remote_ip,omitempty"`
+}
+
+// timing breakdown in seconds.
+type reportTiming struct {
+	DNS      float64 `json:"dns"`
+	Connect  float64 `json:"connect"`
+	TLS      float64 `json:"tls"`
+	Server   float64 `json:"server"`
+	Transfer float64 `json:"transfer"`
+	Total    float64 `json:"total"`
+}
+
+type reportTLS struct {
+	Version     string     `json:"version"`
+	CipherSuite string     `json:"cipher_suite"`
+	ServerName  string     `json:"server_name,omitempty"`
+	Subject     string     `json:"subject,omitempty"`
+	Issuer      string     `json:"issuer,omitempty"`
+	NotAfter    *time.Time `json:"not_after,omitempty"`
+	Verified    bool       `json:"verified"`
+}
+
+// write the request, response, timing and TLS details as one JSON document.
+// size is the number of body bytes received.
+func writeReport(w io.Writer, resp *http.Response, t *timing, size int64) error {
+	var r report
+	req := resp.Request
+	r.Request.Method = req.Method
+	r.Request.URL = req.URL.String()
+	r.Request.Headers = make(map[string][]string, len(req.Header))
+	for k, v := range req.Header {
+		if !ShowSecrets {
+			v = []string{redactHeader(k, strings.Join(v, ","))}
+		}
+		r.Request.Headers[k] = v
+	}
+	r.Response.Status = resp.StatusCode
+	r.Response.StatusText = strings.TrimPrefix(resp.Status, strconv.Itoa(resp.StatusCode)+" ")
+	r.Response.Proto = resp.Proto
+	r.Response.Headers = resp.Header
+	r.Response.BodyLength = size
+	r.Timing = reportTiming{
+		DNS:      t.dns().Seconds(),
+		Connect:  t.connect().Seconds(),
+		TLS:      t.tls().Seconds(),
+		Server:   t.server().Seconds(),
+		Transfer: t.transfer().Seconds(),
+		Total:    t.total().Seconds(),
+	}
+	if resp.TLS != nil {
+		r.TLS = newReportTLS(resp)
+	}
+	r.RemoteIP = remoteIP(t.remoteAddr)
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func newReportTLS(resp *http.Response) *reportTLS {
+	state := resp.TLS
+	info := &reportTLS{
+		Version:     tlsVersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ServerName:  state.ServerName,
+		Verified:    sslVerifyResult(resp) == 0,
+	}
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		info.Subject = cert.Subject.String()
+		info.Issuer = cert.Issuer.String()
+		info.NotAfter = &cert.NotAfter
+	}
+	return info
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// send the request, retrying transient connection errors (and 5xx
+// responses with --retry-all-errors) up to Retry times with exponential
+// backoff, all within --max-time.
+// The request is built again for each attempt, so a file body is reopened.
+// Cancelling ctx stops the request.
+func doRequest(ctx context.Context, w io.Writer, client *http.Client, method string, url *url.URL, trace *httptrace.ClientTrace, t *timing) (*http.Request, *http.Response, error) {
+	delay := RetryDelay
+	begin := time.Now()
+	for attempt := 0; ; attempt++ {
+		ctx := context.WithValue(ctx, outputKey{}, w)
+		if NoSortHeaders {
+			t.order = &headerOrder{}
+			ctx = context.WithValue(ctx, headerOrderKey{}, t.order)
+		}
+		// build the request, with the Authorization header auth if given.
+		build := func(auth string) (*http.Request, error) {
+			req, err := newRequest(method, url, HttpData)
+			if err != nil {
+				return nil, err
+			}
+			if auth != "" {
+				req.Header.Set("Authorization", auth)
+			}
+			if TraceASCII != "" {
+				if err := traceRequest(w, req); err != nil {
+					return nil, err
+				}
+			}
+			req = req.WithContext(httptrace.WithClientTrace(ctx, trace))
+
+			t.connectErr = nil
+			t.remoteAddr = ""
+			t.headSent = 0
+			t.bodySent = nil
+			if req.Body != nil && req.Body != http.NoBody {
+				t.bodySent = &sentBody{r: req.Body}
+				req.Body = struct {
+					io.Reader
+					io.Closer
+				}{t.bodySent, req.Body}
+			}
+			return req, nil
+		}
+		req, err := build("")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		t.start = time.Now()
+		resp, err := client.Do(req)
+		// --digest answers the challenge with a second request.
+		if err == nil && Digest && resp.StatusCode == http.StatusUnauthorized {
+			auth, authErr := digestAuthorization(req, resp)
+			if authErr != nil {
+				resp.Body.Close()
+				return nil, nil, authErr
+			}
+			if auth != "" {
+				resp.Body.Close()
+				if Verbose >= 1 {
+					fprintf(w, "%s %s\n", grayscale(14)("*Digest"), color.YellowString("401 challenge, sending credentials"))
+				}
+				if req, err = build(auth); err != nil {
+					return nil, nil, err
+				}
+				t.start = time.Now()
+				resp, err = client.Do(req)
+			}
+		}
+		retry := err != nil && transientError(ctx, err) || err == nil && RetryAllErrors && resp.StatusCode >= 500
+		if !retry || attempt >= Retry {
+			if err != nil {
+				// resp is nil here, so don't touch its body.
+				return nil, nil, requestError(err, t)
+			}
+			return req, resp, nil
+		}
+
+		reason := ""
+		if err != nil {
+			reason = err.Error()
+		} else {
+			reason = resp.Status
+			resp.Body.Close()
+		}
+		// Ctrl-C or --max-time don't wait out the backoff.
+		if MaxTime > 0 && time.Since(begin)+delay >= MaxTime {
+			return nil, nil, errors.New(color.HiRedString("Operation timed out after %v, retrying in %v would exceed it", MaxTime, delay))
+		}
+		if Verbose >= 1 {
+			fprintf(w, "%s %s\n", grayscale(14)("*Retry"), color.YellowString("%d/%d in %v: %s", attempt+1, Retry, delay, reason))
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, requestError(ctx.Err(), t)
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// report whether err of client.Do is worth another attempt: failing to
+// connect, a reset connection or a network timeout. Certificate errors,
+// redirect limits, the request running out of time or being canceled
+// are not.
+func transientError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return false
+	}
+	if HTTP3 && isQUICTimeout(err) {
+		return true
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		// a TLS alert from the server comes as an OpError too.
+		return opErr.Op != "remote error"
+	}
+	// the server closed the connection before answering.
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// make a client.Do error readable, t tells a failed connect apart.
+func requestError(err error, t *timing) error {
+	if HTTP3 {
+		if err := http3Error(err); err != nil {
+			return err
+		}
+	}
+	// no connection at all, rather than one failing later.
+	if t.connectErr != nil && t.remoteAddr == "" {
+		if e, ok := t.connectErr.(net.Error); ok && e.Timeout() && ConnectTimeout > 0 {
+			return errors.New(color.HiRedString("Connection timed out after %v: %v", ConnectTimeout, t.connectErr))
+		}
+		return errors.New(color.HiRedString("Unable to connect: %v", t.connectErr))
+	}
+	if e, ok := err.(net.Error); ok && e.Timeout() {
+		// the handshake began, but no connection came of it before --max-time.
+		if !t.tlsStart.IsZero() && t.remoteAddr == "" && (MaxTime == 0 || time.Since(t.start) < MaxTime) {
+			return errors.New(color.HiRedString("TLS handshake timed out after %v", TLSTimeout))
+		}
+		return errors.New(color.HiRedString("Operation timed out after %v", MaxTime))
+	}
+	return errors.New(color.HiRedString("failed to read response: %v", err))
+}
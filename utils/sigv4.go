@@ -0,0 +1,193 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// payload hash of a body that can't be read before sending it, accepted
+// by S3. Other services need a -d text body or file read into memory.
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// sign req with AWS Signature Version 4 for --aws-sigv4 "service:region".
+// Credentials come from the --aws-* flags, or else the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+func signSigV4(req *http.Request, now time.Time) error {
+	parts := strings.Split(AWSSigV4, ":")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return errors.New(color.HiRedString("Bad --aws-sigv4 %q, want SERVICE:REGION", AWSSigV4))
+	}
+	service, region := parts[0], parts[1]
+	key, secret, token := awsCredentials()
+	if key == "" || secret == "" {
+		return errors.New(color.HiRedString("--aws-sigv4 needs --aws-access-key and --aws-secret-key, or AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY"))
+	}
+	payload, err := payloadHash(req)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("X-Amz-Date", now.UTC().Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", payload)
+	if token != "" {
+		req.Header.Set("X-Amz-Security-Token", token)
+	}
+
+	req.Header.Set("Authorization", sigV4Authorization(req, service, region, key, secret, payload))
+	return nil
+}
+
+// Authorization header signing req, which has its X-Amz-Date set, in the
+// scope of that date, region and service.
+func sigV4Authorization(req *http.Request, service, region, key, secret, payload string) string {
+	amzDate := req.Header.Get("X-Amz-Date")
+	date := amzDate[:min(len(amzDate), 8)]
+	signed, canonicalHeaders := canonicalHeaders(req)
+	canonical := strings.Join([]string{
+		req.Method,
+		canonicalPath(req, service),
+		canonicalQuery(req),
+		canonicalHeaders,
+		signed,
+		payload,
+	}, "\n")
+	scope := strings.Join([]string{date, region, service, "aws4_request"}, "/")
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonical))}, "\n")
+
+	signingKey := []byte("AWS4" + secret)
+	for _, s := range []string{date, region, service, "aws4_request"} {
+		signingKey = hmacSHA256(signingKey, s)
+	}
+	signature := hex.EncodeToString(hmacSHA256(signingKey, toSign))
+	return fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", key, scope, signed, signature)
+}
+
+// access key, secret key and session token, the flags win over the
+// environment.
+func awsCredentials() (key, secret, token string) {
+	key, secret, token = AWSAccessKey, AWSSecretKey, AWSSessionToken
+	if key == "" {
+		key = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if secret == "" {
+		secret = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+	if token == "" {
+		token = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	return key, secret, token
+}
+
+// hex SHA-256 of the body of req, read from a copy so the body is still
+// sent. Streamed bodies, like files, stdin or gzipped ones, are unsigned.
+func payloadHash(req *http.Request) (string, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return sha256Hex(nil), nil
+	}
+	if req.GetBody == nil || req.Header.Get("Content-Encoding") != "" {
+		return unsignedPayload, nil
+	}
+	body, err := req.GetBody()
+	if err != nil {
+		return "", errors.New(color.HiRedString("Unable to read body for signing: %v", err))
+	}
+	defer body.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return "", errors.New(color.HiRedString("Unable to read body for signing: %v", err))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// signed header names and their canonical lines: Host, Content-Type and
+// the X-Amz-* headers. Other headers may be changed on the way.
+func canonicalHeaders(req *http.Request) (signed, lines string) {
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	values := map[string]string{"host": host}
+	for k, v := range req.Header {
+		name := strings.ToLower(k)
+		if name == "content-type" || strings.HasPrefix(name, "x-amz-") {
+			trimmed := make([]string, len(v))
+			for i := range v {
+				trimmed[i] = strings.Join(strings.Fields(v[i]), " ")
+			}
+			values[name] = strings.Join(trimmed, ",")
+		}
+	}
+	names := make([]string, 0, len(values))
+	for k := range values {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	for _, k := range names {
+		b.WriteString(k + ":" + values[k] + "\n")
+	}
+	return strings.Join(names, ";"), b.String()
+}
+
+// URI of req as signed, S3 paths are escaped once, others twice.
+func canonicalPath(req *http.Request, service string) string {
+	path := req.URL.EscapedPath()
+	if service == "s3" {
+		path = req.URL.Path
+	}
+	if path == "" {
+		return "/"
+	}
+	return awsEscape(path, false)
+}
+
+// query of req with names and values escaped and sorted.
+func canonicalQuery(req *http.Request) string {
+	var pairs []string
+	for k, vs := range req.URL.Query() {
+		for _, v := range vs {
+			pairs = append(pairs, awsEscape(k, true)+"="+awsEscape(v, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+// percent-encode s as SigV4 wants, all but unreserved characters and,
+// unless slash is set, "/".
+func awsEscape(s string, slash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~', c == '/' && !slash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func hmacSHA256(key []byte, s string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(s))
+	return h.Sum(nil)
+}
+
+func sha256Hex(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
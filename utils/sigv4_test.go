@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// requests of the AWS SigV4 test suite, signed for us-east-1 "service"
+// on 20150830T123600Z with the example credentials.
+func TestSigV4Authorization(t *testing.T) {
+	tests := []struct {
+		name        string
+		method, url string
+		contentType string
+		body        string
+		signed      string
+		signature   string
+	}{
+		{"get-vanilla", "GET", "https://example.amazonaws.com/", "", "",
+			"host;x-amz-date", "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"},
+		{"get-vanilla-query-order-key-case", "GET", "https://example.amazonaws.com/?Param2=value2&Param1=value1", "", "",
+			"host;x-amz-date", "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500"},
+		{"post-vanilla", "POST", "https://example.amazonaws.com/", "", "",
+			"host;x-amz-date", "5da7c1a2acd57cee7505fc6676e4e544621c30862966e37dddb68e92efbe5d6b"},
+		{"post-x-www-form-urlencoded", "POST", "https://example.amazonaws.com/", "application/x-www-form-urlencoded", "Param1=value1",
+			"content-type;host;x-amz-date", "ff11897932ad3f4e8b18135d722051e5ac45fc38421b1da7b9d196a0fe09473a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, tt.url, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-Amz-Date", "20150830T123600Z")
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			got := sigV4Authorization(req, "service", "us-east-1", "AKIDEXAMPLE", "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", sha256Hex([]byte(tt.body)))
+			want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=" +
+				tt.signed + ", Signature=" + tt.signature
+			if got != want {
+				t.Errorf("got  %s\nwant %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSignSigV4(t *testing.T) {
+	AWSSigV4, AWSAccessKey, AWSSecretKey, AWSSessionToken = "service:us-east-1", "AKIDEXAMPLE", "secret", "token"
+	defer func() { AWSSigV4, AWSAccessKey, AWSSecretKey, AWSSessionToken = "", "", "", "" }()
+
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	if err := signSigV4(req, time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)); err != nil {
+		t.Fatal(err)
+	}
+	for k, want := range map[string]string{
+		"X-Amz-Date":           "20150830T123600Z",
+		"X-Amz-Content-Sha256": sha256Hex(nil),
+		"X-Amz-Security-Token": "token",
+	} {
+		if got := req.Header.Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+	auth := req.Header.Get("Authorization")
+	if !strings.Contains(auth, "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,") {
+		t.Errorf("Authorization = %q, want the session token signed", auth)
+	}
+}
+
+func TestCanonicalPathAndQuery(t *testing.T) {
+	tests := []struct {
+		url, service string
+		path, query  string
+	}{
+		{"https://h/", "service", "/", ""},
+		{"https://h", "service", "/", ""},
+		{"https://h/example%20space/", "service", "/example%2520space/", ""},
+		{"https://h/example%20space/", "s3", "/example%20space/", ""},
+		{"https://h/?b=2&a=1&a=0", "service", "/", "a=0&a=1&b=2"},
+		{"https://h/?k=a%20b&x=%2F~", "service", "/", "k=a%20b&x=%2F~"},
+	}
+	for _, tt := range tests {
+		req, err := http.NewRequest("GET", tt.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := canonicalPath(req, tt.service); got != tt.path {
+			t.Errorf("canonicalPath(%s, %s) = %q, want %q", tt.url, tt.service, got, tt.path)
+		}
+		if got := canonicalQuery(req); got != tt.query {
+			t.Errorf("canonicalQuery(%s) = %q, want %q", tt.url, got, tt.query)
+		}
+	}
+}
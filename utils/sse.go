@@ -0,0 +1,83 @@
+package utils
+
+import (
+	"bufio"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+// report whether resp is a text/event-stream, shown as events.
+func isEventStream(resp *http.Response) bool {
+	mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	return err == nil && mediaType == "text/event-stream"
+}
+
+// one Server-Sent Event, fields the stream didn't give are empty.
+type sseEvent struct {
+	name, id, retry string
+	data            []string
+}
+
+// print the events of the body as they arrive, until the server closes
+// the stream. An event is only complete at its blank line, so one cut off
+// at the end is dropped.
+func showEvents(w io.Writer, body io.Reader) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(nil, 1<<20)
+	var ev sseEvent
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			if ev.data != nil || ev.name != "" {
+				showEvent(w, ev)
+			}
+			ev = sseEvent{}
+			continue
+		}
+		// lines starting with a colon are comments, keep-alives mostly.
+		if strings.HasPrefix(line, ":") {
+			continue
+		}
+		field, value := line, ""
+		if i := strings.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], strings.TrimPrefix(line[i+1:], " ")
+		}
+		switch field {
+		case "event":
+			ev.name = value
+		case "data":
+			ev.data = append(ev.data, value)
+		case "id":
+			ev.id = value
+		case "retry":
+			ev.retry = value
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return bodyError("Unable to read event stream: %v", err)
+	}
+	return nil
+}
+
+// print ev, as "message" when the stream names no event type.
+func showEvent(w io.Writer, ev sseEvent) {
+	name := ev.name
+	if name == "" {
+		name = "message"
+	}
+	fprintf(w, "%s %s", grayscale(14)("Event:"), color.CyanString(name))
+	if ev.id != "" {
+		fprintf(w, " %s", grayscale(14)("(id %s)", ev.id))
+	}
+	if ev.retry != "" {
+		fprintf(w, " %s", grayscale(14)("(retry %sms)", ev.retry))
+	}
+	fprintf(w, "\n")
+	for _, d := range ev.data {
+		fprintf(w, "%s %s\n", grayscale(14)("Data:"), color.CyanString(truncateLine(d)))
+	}
+}
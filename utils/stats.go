@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// print the --stats line: bytes sent and received, status and elapsed time.
+// Heads are counted as HTTP/1.1 text, body is the number of bytes received.
+func showStats(w io.Writer, resp *http.Response, t *timing, body int64) {
+	req := resp.Request
+	sent := int64(len(req.Method)+len(req.URL.RequestURI())+len(req.Proto)+4) + t.headSent + 2
+	if t.bodySent != nil {
+		sent += t.bodySent.n
+	}
+	received := int64(len(resp.Proto)+len(resp.Status)+3) + headerSize(resp.Header) + 2 + body
+	fprintf(w, "%s %s\n", grayscale(14)("Stats:"), color.CyanString("sent %d bytes, received %d bytes, %s in %v",
+		sent, received, resp.Status, t.total().Round(time.Millisecond)))
+}
+
+// size of h written as "Name: value\r\n" lines.
+func headerSize(h http.Header) int64 {
+	var n int64
+	for k, vs := range h {
+		for _, v := range vs {
+			n += int64(len(k) + len(v) + 4)
+		}
+	}
+	return n
+}
+
+// bytes of a request body kept for showing it.
+const maxBodyPreview = 1024
+
+// request body as it is sent, counting the bytes and keeping the first
+// maxBodyPreview of them, so even stdin can be shown after the request.
+type sentBody struct {
+	r    io.Reader
+	n    int64
+	head []byte
+}
+
+func (b *sentBody) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.n += int64(n)
+	if keep := maxBodyPreview - len(b.head); keep > 0 {
+		if keep > n {
+			keep = n
+		}
+		b.head = append(b.head, p[:keep]...)
+	}
+	return n, err
+}
+
+// show the request body sent, prefixed like the request head.
+func showRequestBody(w io.Writer, body *sentBody) {
+	head := body.head
+	// the preview may end inside a rune.
+	for i := 0; i < utf8.UTFMax-1 && len(head) > 0 && !utf8.Valid(head) && int64(len(head)) < body.n; i++ {
+		head = head[:len(head)-1]
+	}
+	if !utf8.Valid(head) || bytes.IndexByte(head, 0) >= 0 {
+		fprintf(w, "> %s\n", grayscale(14)("[%d bytes of binary data]", body.n))
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSuffix(string(head), "\n"), "\n") {
+		fprintf(w, "> %s\n", color.CyanString(strings.TrimSuffix(line, "\r")))
+	}
+	if more := body.n - int64(len(head)); more > 0 {
+		fprintf(w, "> %s\n", grayscale(14)("[%d more bytes]", more))
+	}
+}
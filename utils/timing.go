@@ -0,0 +1,184 @@
+package utils
+
+import (
+	"crypto/tls"
+	"io"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// timestamps of a request and the connection it used, collected by httptrace.
+// With redirects or retries they describe the last connection.
+type timing struct {
+	start        time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+	wroteRequest time.Time
+	firstByte    time.Time
+	done         time.Time
+
+	remoteAddr string // address of the connection used
+	connectErr error  // last failed connect
+
+	// bytes of the request head and body sent, for --stats and -v.
+	headSent int64
+	bodySent *sentBody
+
+	order *headerOrder // header order of the response, for --no-sort-headers
+}
+
+// trace filling t, which also prints the connection events under -vv.
+func newClientTrace(w io.Writer, t *timing) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			t.dnsStart = time.Now()
+			tracef(w, "Resolving %s", info.Host)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			t.dnsDone = time.Now()
+			if info.Err != nil {
+				tracef(w, "Resolve failed: %v", info.Err)
+				return
+			}
+			tracef(w, "Resolved to %v", info.Addrs)
+		},
+		ConnectStart: func(network, addr string) {
+			t.connectStart = time.Now()
+			tracef(w, "Connecting to %s (%s)", addr, network)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			t.connectDone = time.Now()
+			// client.Do returns the error, so it can be retried.
+			// It is kept for a clearer message if no address works.
+			if err != nil {
+				t.connectErr = err
+				tracef(w, "Connect failed: %v", err)
+				return
+			}
+
+			bannerf(w, "\n%s%s\n", color.GreenString("Connected to "), color.CyanString(addr))
+		},
+		TLSHandshakeStart: func() {
+			t.tlsStart = time.Now()
+			tracef(w, "TLS handshake started")
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			t.tlsDone = time.Now()
+			if err != nil {
+				tracef(w, "TLS handshake failed: %v", err)
+				return
+			}
+			tracef(w, "TLS handshake done, cipher %s", tls.CipherSuiteName(state.CipherSuite))
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.remoteAddr = info.Conn.RemoteAddr().String()
+			if t.order != nil {
+				if c, ok := info.Conn.(*headConn); ok {
+					c.expect(t.order)
+				} else {
+					t.order.set(nil)
+				}
+			}
+			if Verbose < 1 {
+				return
+			}
+			if info.Reused {
+				fprintf(w, "%s %s %s\n", grayscale(14)("*Re-using connection to"), color.CyanString(t.remoteAddr),
+					grayscale(14)("(idle %v)", info.IdleTime.Round(time.Millisecond)))
+			} else {
+				fprintf(w, "%s %s\n", grayscale(14)("*New connection to"), color.CyanString(t.remoteAddr))
+			}
+		},
+		WroteHeaderField: func(key string, value []string) {
+			for _, v := range value {
+				t.headSent += int64(len(key) + len(v) + 4)
+			}
+		},
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			t.wroteRequest = time.Now()
+			tracef(w, "Request sent")
+		},
+		GotFirstResponseByte: func() {
+			t.firstByte = time.Now()
+			tracef(w, "First response byte received")
+		},
+	}
+}
+
+// print a connection event under -vv.
+func tracef(w io.Writer, format string, a ...interface{}) {
+	if Verbose >= 2 {
+		fprintf(w, "%s\n", grayscale(14)("*"+format, a...))
+	}
+}
+
+// duration between two timestamps, zero when a phase didn't happen
+// (e.g. no DNS for an IP address, no TLS for plaintext).
+func between(a, b time.Time) time.Duration {
+	if a.IsZero() || b.IsZero() {
+		return 0
+	}
+	return b.Sub(a)
+}
+
+func (t *timing) dns() time.Duration      { return between(t.dnsStart, t.dnsDone) }
+func (t *timing) connect() time.Duration  { return between(t.connectStart, t.connectDone) }
+func (t *timing) tls() time.Duration      { return between(t.tlsStart, t.tlsDone) }
+func (t *timing) server() time.Duration   { return between(t.wroteRequest, t.firstByte) }
+func (t *timing) transfer() time.Duration { return between(t.firstByte, t.done) }
+func (t *timing) total() time.Duration    { return between(t.start, t.done) }
+
+// print timing breakdown like httpstat.
+func showTiming(w io.Writer, t *timing) {
+	rows := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"DNS Lookup", t.dns()},
+		{"TCP Connection", t.connect()},
+		{"TLS Handshake", t.tls()},
+		{"Server Processing", t.server()},
+		{"Content Transfer", t.transfer()},
+		{"Total", t.total()},
+	}
+	fprintf(w, "\n")
+	for _, r := range rows {
+		fprintf(w, "%s %s\n", grayscale(14)("%-18s", r.name+":"), color.CyanString("%v", r.d.Round(time.Microsecond)))
+	}
+}
+
+// writer stamping each line with the time since the request of t
+// started, for --trace-time. Empty lines stay empty.
+type traceTimeWriter struct {
+	w       io.Writer
+	t       *timing
+	midLine bool
+}
+
+func (tw *traceTimeWriter) Write(p []byte) (int, error) {
+	var b []byte
+	for _, c := range p {
+		if !tw.midLine && c != '\n' {
+			var elapsed time.Duration
+			if !tw.t.start.IsZero() {
+				elapsed = time.Since(tw.t.start)
+			}
+			b = append(b, grayscale(14)("%8.3fs ", elapsed.Seconds())...)
+			tw.midLine = true
+		}
+		b = append(b, c)
+		if c == '\n' {
+			tw.midLine = false
+		}
+	}
+	if _, err := tw.w.Write(b); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,152 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+)
+
+// TLS config shared by all connections, the transport fills in
+// ServerName for each host.
+func newTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{
+		InsecureSkipVerify: InsecureSkip,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if TLSMin != "" {
+		v, err := parseTLSVersion("--tls-min", TLSMin)
+		if err != nil {
+			return nil, err
+		}
+		config.MinVersion = v
+	}
+	if TLSMax != "" {
+		v, err := parseTLSVersion("--tls-max", TLSMax)
+		if err != nil {
+			return nil, err
+		}
+		config.MaxVersion = v
+	}
+	// --tls-max alone may go below the default minimum, to test old
+	// protocols.
+	if TLSMin == "" && config.MaxVersion != 0 && config.MaxVersion < config.MinVersion {
+		config.MinVersion = config.MaxVersion
+	}
+	if config.MaxVersion != 0 && config.MaxVersion < config.MinVersion {
+		return nil, errors.New(color.HiRedString("--tls-max %s is lower than --tls-min %s", TLSMax, TLSMin))
+	}
+
+	if ClientCert != "" {
+		// like curl, the key may be in the certificate file.
+		key := ClientKey
+		if key == "" {
+			key = ClientCert
+		}
+		cert, err := tls.LoadX509KeyPair(ClientCert, key)
+		if err != nil {
+			return nil, errors.New(color.HiRedString("Unable to load client certificate: %v", err))
+		}
+		config.Certificates = []tls.Certificate{cert}
+	} else if ClientKey != "" {
+		return nil, errors.New(color.HiRedString("--key needs --cert"))
+	}
+
+	if CACert != "" {
+		pool, err := loadCertPool(CACert)
+		if err != nil {
+			return nil, err
+		}
+		config.RootCAs = pool
+	}
+	rootCAs = config.RootCAs
+	return config, nil
+}
+
+// roots the transport verifies against, nil for the system ones. Kept
+// for checking certificates again under -k.
+var rootCAs *x509.CertPool
+
+// map a --tls-min/--tls-max value to its tls.Version constant.
+func parseTLSVersion(flag, v string) (uint16, error) {
+	switch v {
+	case "1.0":
+		return tls.VersionTLS10, nil
+	case "1.1":
+		return tls.VersionTLS11, nil
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, errors.New(color.HiRedString("Bad %s %q, want 1.0, 1.1, 1.2 or 1.3", flag, v))
+}
+
+// name of a negotiated TLS version, unknown ones show the raw number.
+func tlsVersionName(v uint16) string {
+	switch v {
+	case tls.VersionTLS10:
+		return "TLSv1.0"
+	case tls.VersionTLS11:
+		return "TLSv1.1"
+	case tls.VersionTLS12:
+		return "TLSv1.2"
+	case tls.VersionTLS13:
+		return "TLSv1.3"
+	}
+	return fmt.Sprintf("TLS (0x%04x)", v)
+}
+
+// load a PEM bundle for --cacert. A bundle without certificates is an
+// error, rather than silently falling back to the system pool.
+func loadCertPool(name string) (*x509.CertPool, error) {
+	pem, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, errors.New(color.HiRedString("Unable to read CA bundle: %v", err))
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New(color.HiRedString("No valid certificates in CA bundle %s", name))
+	}
+	return pool, nil
+}
+
+// print the server certificate chain, expiry is yellow when near
+// and red once passed.
+func showCertificates(w io.Writer, state *tls.ConnectionState) {
+	for i, cert := range state.PeerCertificates {
+		fprintf(w, "%s\n", grayscale(14)("*Certificate %d:", i))
+		fprintf(w, "*  %s %s\n", grayscale(14)("Subject:"), color.CyanString(cert.Subject.String()))
+		fprintf(w, "*  %s %s\n", grayscale(14)("Issuer:"), color.CyanString(cert.Issuer.String()))
+		var names []string
+		names = append(names, cert.DNSNames...)
+		for _, ip := range cert.IPAddresses {
+			names = append(names, ip.String())
+		}
+		if len(names) > 0 {
+			fprintf(w, "*  %s %s\n", grayscale(14)("SANs:"), color.CyanString(strings.Join(names, ", ")))
+		}
+		fprintf(w, "*  %s %s\n", grayscale(14)("Valid:"), color.CyanString("%s - %s",
+			cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339)))
+
+		days := int(time.Until(cert.NotAfter).Hours() / 24)
+		expiry := color.CyanString
+		switch {
+		case days < 0:
+			expiry = color.HiRedString
+		case days < 30:
+			expiry = color.YellowString
+		}
+		if days < 0 {
+			fprintf(w, "*  %s %s\n", grayscale(14)("Expiry:"), expiry("expired %d days ago", -days))
+		} else {
+			fprintf(w, "*  %s %s\n", grayscale(14)("Expiry:"), expiry("%d days left", days))
+		}
+	}
+}
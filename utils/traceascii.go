@@ -0,0 +1,133 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+var (
+	// --trace-ascii file, nil when tracing to the output.
+	traceFile *os.File
+	traceMu   sync.Mutex
+)
+
+// create the --trace-ascii file, "-" traces to the output of each URL.
+func openTrace() error {
+	if TraceASCII == "" || TraceASCII == "-" {
+		return nil
+	}
+	f, err := os.Create(TraceASCII)
+	if err != nil {
+		return errors.New(color.HiRedString("Unable to create trace file: %v", err))
+	}
+	traceFile = f
+	return nil
+}
+
+func closeTrace() {
+	if traceFile != nil {
+		traceFile.Close()
+		traceFile = nil
+	}
+}
+
+// dump req as it will be sent. The body is read and put back.
+func traceRequest(w io.Writer, req *http.Request) error {
+	dump, err := httputil.DumpRequestOut(req, true)
+	if err != nil {
+		return errors.New(color.HiRedString("Unable to trace request: %v", err))
+	}
+	writeTrace(w, "=> Send", dump)
+	return nil
+}
+
+// dump the head of resp as received, and its body as it is read, so an
+// endless body like an event stream is traced too.
+func traceResponse(w io.Writer, resp *http.Response) error {
+	dump, err := httputil.DumpResponse(resp, false)
+	if err != nil {
+		return errors.New(color.HiRedString("Unable to trace response: %v", err))
+	}
+	writeTrace(w, "<= Recv", dump)
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{&traceReader{r: resp.Body, w: w}, resp.Body}
+	return nil
+}
+
+// reader tracing each piece of the response body read through it.
+type traceReader struct {
+	r io.Reader
+	w io.Writer
+}
+
+func (t *traceReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if n > 0 {
+		var b strings.Builder
+		writeTraceData(&b, "<= Recv", p[:n])
+		writeTraceOut(t.w, b.String())
+	}
+	return n, err
+}
+
+// write a dump like curl --trace-ascii, the head as text and the body
+// as text or, when binary, as hex.
+func writeTrace(w io.Writer, dir string, dump []byte) {
+	head, body := dump, []byte(nil)
+	if i := bytes.Index(dump, []byte("\r\n\r\n")); i >= 0 {
+		head, body = dump[:i+4], dump[i+4:]
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s header, %d bytes (0x%x)\n", dir, len(head), len(head))
+	writeTraceText(&b, head)
+	writeTraceData(&b, dir, body)
+	writeTraceOut(w, b.String())
+}
+
+func writeTraceData(b *strings.Builder, dir string, data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "%s data, %d bytes (0x%x)\n", dir, len(data), len(data))
+	if utf8.Valid(data) && bytes.IndexByte(data, 0) < 0 {
+		writeTraceText(b, data)
+	} else {
+		b.WriteString(hex.Dump(data))
+	}
+}
+
+// write s to the trace file, or to w when tracing to the output.
+func writeTraceOut(w io.Writer, s string) {
+	traceMu.Lock()
+	defer traceMu.Unlock()
+	if traceFile != nil {
+		_, _ = io.WriteString(traceFile, s)
+		return
+	}
+	fprintf(w, "%s", s)
+}
+
+// write text lines, each with the offset of its first byte.
+func writeTraceText(b *strings.Builder, text []byte) {
+	offset := 0
+	for _, line := range strings.SplitAfter(string(text), "\n") {
+		if line == "" {
+			continue
+		}
+		fmt.Fprintf(b, "%04x: %s\n", offset, strings.TrimRight(line, "\r\n"))
+		offset += len(line)
+	}
+}
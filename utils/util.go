@@ -1,21 +1,27 @@
 package utils
 
 import (
+	"bytes"
 	"context"
-	"crypto/tls"
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/fatih/color"
 	"io"
 	"io/ioutil"
-	"log"
 	"net"
 	"net/http"
-	"net/http/httptrace"
 	"net/url"
+	"os"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"golang.org/x/term"
 )
 
 type headers []string
@@ -33,6 +39,39 @@ func (h *headers) Set(v string) error {
 	return nil
 }
 
+// repeatable string flag.
+type stringList []string
+
+func (l stringList) String() string { return strings.Join(l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// -v and -vv, both raise Verbose to their level.
+type verbosity struct {
+	level *int
+	value int
+}
+
+func (v verbosity) String() string { return "" }
+
+func (v verbosity) Set(s string) error {
+	on, err := strconv.ParseBool(s)
+	if on && *v.level < v.value {
+		*v.level = v.value
+	}
+	return err
+}
+
+func (v verbosity) IsBoolFlag() bool { return true }
+
+// VerboseFlag returns the flag.Value for a verbosity level.
+func VerboseFlag(level int) flag.Value {
+	return verbosity{level: &Verbose, value: level}
+}
+
 func (h headers) Len() int      { return len(h) }
 func (h headers) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
 func (h headers) Less(i, j int) bool {
@@ -73,166 +112,1249 @@ func (h headers) Less(i, j int) bool {
 
 var (
 	// Command line flags
-	HttpMethod       string // http method
-	HttpResponseHead bool   // response head
-	HttpConnectInfo  bool   // connect information
+	HttpMethod       string  // http method
+	HttpMethodSet    bool    // -X was given
+	CustomMethod     bool    // allow non-standard method
+	HttpResponseHead bool    // response head only
+	Verbose          int     // 1 for -v, 2 for -vv
+	HttpData         string  // request body
+	CustomHeaders    headers // request headers
+	Trailers         headers // request trailers, sent after a chunked body
+	UserAgent        string  // User-Agent header
+	BasicAuth        string  // user:password
+	Digest           bool    // digest auth with the BasicAuth credentials
+	BearerToken      string  // bearer auth token
+	Netrc            bool    // basic auth from ~/.netrc
+	NetrcFile        string  // basic auth from this .netrc file
+	AWSSigV4         string  // service:region to sign requests for
+	AWSAccessKey     string  // AWS access key ID
+	AWSSecretKey     string  // AWS secret access key
+	AWSSessionToken  string  // AWS session token, for temporary credentials
+	Cookie           string  // cookies or cookie file to send
+	CookieJar        string  // file to save cookies to
+	OutputFile       string  // write body to file
+	AppendOutput     bool    // append to OutputFile instead of truncating it
+	Compressed       bool    // ask for a compressed response
+	Range            string  // byte range to get
+	PrettyJSON       bool    // indent JSON bodies
+	Silent           bool    // body only, no banners
+	NoColor          bool    // plain output
+	FailOnError      bool    // error on HTTP 4xx/5xx
+	ExitFromStatus   bool    // exit code from HTTP status
+	InsecureSkip     bool    // skip TLS verification
+	FollowRedirects  bool    // follow 3xx responses
+	MaxRedirects     int     // redirect limit
+	Retry            int     // retries on connection errors
+	Parallel         int     // concurrent URLs
+	RetryAllErrors   bool    // retry on 5xx too
+	ShowTiming       bool    // timing breakdown
+	WriteOut         string  // -w format
+
+	// Connection
+	IPv4Only bool       // resolve to IPv4 only
+	IPv6Only bool       // resolve to IPv6 only
+	Resolve  stringList // HOST:PORT:ADDRESS overrides
+	ProxyURL string     // proxy to use
+	NoProxy  string     // hosts reached without proxy
+	HTTP11   bool       // don't use HTTP/2
+	HTTP3    bool       // use HTTP/3 over QUIC
+
+	UnixSocket    string        // unix socket to connect to
+	ConnectTo     stringList    // HOST1:PORT1:HOST2:PORT2 overrides
+	Interface     string        // source interface or address
+	LocalPort     int           // source port
+	DNSServer     string        // DNS server instead of the system resolver
+	HostHeader    string        // Host header instead of the URL host
+	NoExpand      bool          // send -H values without expanding $VARS
+	PathAsIs      bool          // send the URL path as typed, kept in RawPath
+	NoKeepAlive   bool          // a new connection for every request
+	KeepAliveTime time.Duration // how long idle connections are kept
+
+	Expect100Timeout time.Duration // wait for 100 Continue before sending the body
+	NoExpect100      bool          // never send Expect: 100-continue
+	TLSTimeout       time.Duration // TLS, or QUIC, handshake limit
+	MaxIdleConns     int           // idle connections kept over all hosts
+
+	// TLS
+	ClientCert string // client certificate file
+	ClientKey  string // client key file
+	CACert     string // CA bundle file
+	TLSMin     string // lowest TLS version
+	TLSMax     string // highest TLS version
+
+	// Request body
+	DataURLEncode stringList // --data-urlencode pairs
+	FormFields    stringList // -F multipart fields
+	JSONData      string     // --json body
+
+	CompressRequest bool // gzip the request body
+
+	// Transfer
+	LimitRate   byteSize // max download speed
+	MaxFileSize byteSize // max body size
+	NoDecode    bool     // show body bytes without charset decoding
 
-	ShowVersion bool	// show program version
+	// Output
+	ShowSecrets bool // don't redact credentials under -vv
+	HeadJSON    bool // response head as JSON
+	OutputJSON  bool // whole transaction as JSON
+	Raw         bool // response as received, without decoration or decoding
+
+	DumpHeader  string // file for the response head
+	ETagSave    string // file for the response validators
+	ETagCompare string // file of validators to send
+	ShowStats   bool   // bytes sent and received
+	OptionsMode bool   // show Allow and CORS headers of an OPTIONS request
+	TraceASCII  string // file for the wire dump
+	TraceTime   bool   // stamp verbose lines with the time since the request started
+
+	HeaderFilter  stringList // response headers to show
+	GrepHeader    string     // pattern of response headers to show
+	NoSortHeaders bool       // response headers in the order received
+
+	SHA256 digestFlag // expected SHA-256 of the body
+	MD5    digestFlag // expected MD5 of the body
+
+	PreviewLines = previewLines{briefHead, briefTail} // lines of the brief body
+	PreviewBytes byteSize                             // bytes of the brief body
+
+	MaxLineLength int // characters of a shown body line, 0 means the terminal width
+
+	// Timeouts
+	MaxTime        time.Duration // whole request
+	ConnectTimeout time.Duration // connect only
+	RetryDelay     time.Duration // first wait between retries
+
+	Repeat         int           // times to send the request, -1 for ever
+	RepeatInterval time.Duration // wait between repeats
+
+	ShowVersion bool       // show program version
+	URLArgs     stringList // --url entries
+	GlobOff     bool       // no [1-10] and {a,b} expansion of URLs
+	WebSocket   bool       // upgrade http URLs to a WebSocket
+	SSE         bool       // show the body as Server-Sent Events
+	Stream      bool       // copy the body to stdout as it arrives
+	DryRun      bool       // show the requests without sending them
+	CurlCommand bool       // show curl commands instead of sending
 
 	Version = "Dev"
 )
 
-func printf(format string, a ...interface{}) (n int, err error) {
-	return fmt.Fprintf(color.Output, format, a...)
+// StatusError is returned by VisitURL when the server answers
+// with a 4xx or 5xx status and --fail or --exit-code-from-status is set.
+type StatusError struct {
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return "The requested URL returned error: " + e.Status
+}
+
+func fprintf(w io.Writer, format string, a ...interface{}) (n int, err error) {
+	return fmt.Fprintf(w, format, a...)
+}
+
+// fprintf for banners and other decoration, which --silent and
+// the JSON outputs suppress.
+func bannerf(w io.Writer, format string, a ...interface{}) {
+	if !Silent && !HeadJSON && !OutputJSON && !Raw {
+		fprintf(w, format, a...)
+	}
 }
 
 func grayscale(code color.Attribute) func(string, ...interface{}) string {
 	return color.New(code + 232).SprintfFunc()
 }
 
-func VisitURL(url *url.URL) error {
-	// TODO: data body have not set flag
-	req, err := newRequest(HttpMethod, url, "")
-	// We add req User-Agent
-	// // TODO: modify this param later
-	req.Header.Add("User-Agent", "curl/7.77.0")
+// VisitURL visits a single URL.
+func VisitURL(u *url.URL) error {
+	return VisitURLs([]*url.URL{u})
+}
+
+// VisitURLs visits urls one by one with a shared client. A failed URL
+// doesn't stop the others, its error is printed after its output.
+func VisitURLs(urls []*url.URL) error {
+	if IPv4Only && IPv6Only {
+		return errors.New(color.HiRedString("-4 and -6 can't be used together"))
+	}
+	method, err := checkMethod(HttpMethod)
 	if err != nil {
 		return err
 	}
+	if err := prepareBody(); err != nil {
+		return err
+	}
+	if GrepHeader != "" {
+		if headerPattern, err = regexp.Compile(GrepHeader); err != nil {
+			return errors.New(color.HiRedString("Bad --grep-header pattern: %v", err))
+		}
+	}
+	lineLength = MaxLineLength
+	if lineLength == 0 {
+		lineLength = terminalWidth()
+	}
+	if OptionsMode && !HttpMethodSet {
+		method = http.MethodOptions
+	}
+	// -I alone only wants headers, so don't download the body.
+	if (HttpResponseHead || HeadJSON) && !OptionsMode && !HttpMethodSet && HttpData == "" && len(FormFields) == 0 {
+		method = http.MethodHead
+	}
+	client, err := newClient()
+	if err != nil {
+		return err
+	}
+	if err := openTrace(); err != nil {
+		return err
+	}
+	defer closeTrace()
+	if Netrc || NetrcFile != "" {
+		netrc = loadNetrc(color.Output)
+	}
+	if Digest && BasicAuth == "" {
+		return errors.New(color.HiRedString("--digest needs -u user:password"))
+	}
+	// ask for the password before requests may run in parallel.
+	if BasicAuth != "" {
+		if _, _, err := basicAuth(); err != nil {
+			return err
+		}
+	}
+	replayBody = Retry > 0 || len(urls) > 1 || Repeat != 0 || Digest
 
-	// TODO: count time cost
-
-	trace := &httptrace.ClientTrace{
-		ConnectDone: func(net, addr string, err error) {
-			if err != nil {
-				log.Fatalf("unable to connect to host %v: %v", addr, err)
-			}
+	if DryRun {
+		return showDryRun(color.Output, method, urls)
+	}
+	if CurlCommand {
+		return showCurl(color.Output, method, urls)
+	}
+	err = visitAll(client, method, urls)
+	// one -c file for the cookies of all URLs, also when some failed.
+	if CookieJar != "" {
+		if jarErr := savedCookies.write(CookieJar); err == nil {
+			err = jarErr
+		}
+	}
+	return err
+}
 
-			printf("\n%s%s\n", color.GreenString("Connected to "), color.CyanString(addr))
-		},
+// request each of urls the way the flags ask for.
+func visitAll(client *http.Client, method string, urls []*url.URL) error {
+	if Repeat != 0 {
+		return visitRepeat(color.Output, client, method, urls)
+	}
+	if len(urls) == 1 {
+		return visit(color.Output, client, method, urls[0])
+	}
+	if Parallel > 1 {
+		return visitParallel(client, method, urls)
+	}
+	result := &failedURLs{total: len(urls)}
+	for _, u := range urls {
+		w := color.Output
+		bannerf(w, "\n%s %s\n", color.HiBlueString("=="), color.CyanString(u.String()))
+		if err := visit(w, client, method, u); err != nil {
+			result.failed++
+			result.last = err
+			_, _ = fmt.Fprintln(os.Stderr, err)
+		}
+	}
+	if result.failed > 0 {
+		return result
 	}
+	return nil
+}
+
+// failedURLs is returned by VisitURLs when some of several URLs failed.
+// It unwraps to the last failure, so its status can pick the exit code.
+type failedURLs struct {
+	failed, total int
+	last          error
+}
 
-	req = req.WithContext(httptrace.WithClientTrace(context.Background(), trace))
+func (e *failedURLs) Error() string {
+	return color.HiRedString("%d of %d URLs failed", e.failed, e.total)
+}
+
+func (e *failedURLs) Unwrap() error { return e.last }
+
+// send one request and show the response.
+func visit(w io.Writer, client *http.Client, method string, url *url.URL) error {
+	if isWebSocket(url) {
+		return visitWebSocket(w, client, url)
+	}
+	if url.Scheme == "https" && InsecureSkip {
+		bannerf(w, "%s\n", color.YellowString("Warning: TLS certificate verification is disabled"))
+	}
 
-	tr := &http.Transport{
-		Proxy: http.ProxyFromEnvironment,
-		MaxIdleConns: 100,
-		IdleConnTimeout:       90 * time.Second,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		ForceAttemptHTTP2:     true,
+	t := &timing{}
+	// the response itself goes to out, without --trace-time stamps.
+	out := w
+	if TraceTime {
+		w = &traceTimeWriter{w: w, t: t}
+	}
+	trace := newClientTrace(w, t)
+	req, resp, err := doRequest(context.Background(), w, client, method, url, trace, t)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	// Print SSL/TLS version which is used for connection
+	connectedVia := "plaintext"
+	if resp.TLS != nil {
+		connectedVia = tlsVersionName(resp.TLS.Version)
+	}
+	bannerf(w, "\n%s %s\n", color.GreenString("Connected via"), color.CyanString("%s, %s", connectedVia, resp.Proto))
+	if resp.StatusCode == http.StatusPartialContent {
+		bannerf(w, "%s %s\n", color.GreenString("Partial content"), color.CyanString(resp.Header.Get("Content-Range")))
+	}
+	if Verbose >= 1 && resp.TLS != nil {
+		showCertificates(w, resp.TLS)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		if hint := authHint(resp); hint != "" {
+			bannerf(w, "%s\n", color.YellowString("Warning: %s", hint))
+		}
 	}
 
-	// TODO: choose IPv4 or IPv6
+	if CookieJar != "" {
+		savedCookies.add(resp)
+	}
+	if ETagSave != "" {
+		if err := saveValidators(ETagSave, resp); err != nil {
+			return err
+		}
+	}
 
-	switch url.Scheme {
-	case "https":
-		host, _, err := net.SplitHostPort(req.Host)
-		if err != nil {
-			host = req.Host
+	// show connect-info, the response head is shown only once
+	// when -I is given too.
+	if Verbose >= 1 {
+		showRequestInfo(w, req, t.bodySent)
+		fprintf(w, "%s\n", grayscale(14)("*Get response from server"))
+	}
+	switch {
+	case OutputJSON:
+		// the head is part of the report.
+	case HeadJSON:
+		if err := writeHeadJSON(out, resp); err != nil {
+			return err
 		}
+	case Raw:
+		if err := writeHeaderText(out, resp); err != nil {
+			return err
+		}
+	case Verbose >= 1 || HttpResponseHead:
+		showResponseHeader(w, resp)
+	}
+	if DumpHeader != "" {
+		if err := dumpHeader(out, resp, DumpHeader); err != nil {
+			return err
+		}
+	}
 
-		tr.TLSClientConfig = &tls.Config{
-			ServerName:         host,
-			InsecureSkipVerify: false,
-			MinVersion:         tls.VersionTLS12,
+	if LimitRate > 0 {
+		limitBody(resp, int64(LimitRate))
+	}
+	// HEAD, 304 and the like download nothing whatever their Content-Length.
+	if MaxFileSize > 0 && hasBody(resp) {
+		if err := limitBodySize(resp, int64(MaxFileSize)); err != nil {
+			return err
+		}
+	}
+	// bytes as received, before any decoding.
+	downloaded := countBody(resp)
+	if TraceASCII != "" {
+		if err := traceResponse(w, resp); err != nil {
+			return err
 		}
 	}
 
-	client := &http.Client{
-		Transport: tr,
+	var raw, decoded *countingReader
+	// --raw keeps the body encoded.
+	if Compressed && !Raw && hasBody(resp) {
+		if raw, decoded, err = decodeBody(resp); err != nil {
+			return err
+		}
 	}
 
-	resp, err := client.Do(req)
-	defer resp.Body.Close()
+	// after decoding, so the digest is of the content.
+	digests := hashBody(resp)
+
+	// --fail drops the body of an error page.
+	if FailOnError && resp.StatusCode >= 400 {
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	// only a body read to its end can be checked against Content-Length.
+	bodyRead := true
+	switch {
+	case req.Method == http.MethodHead:
+		// there is no body.
+		bodyRead = false
+	case OptionsMode && !HeadJSON:
+		// --head-json has Allow among the headers, and stays JSON.
+		showOptions(w, resp)
+		bodyRead = false
+	case resp.StatusCode == http.StatusNotModified:
+		bannerf(w, "%s %s\n", color.GreenString("Not modified:"), color.CyanString("%s", url))
+		bodyRead = false
+	case OutputFile == "-":
+		// body goes to stdout as is, without --trace-time stamps.
+		err = saveResponseBody(out, resp, OutputFile)
+	case OutputFile != "":
+		// body goes to the file only.
+		err = saveResponseBody(w, resp, OutputFile)
+	case OutputJSON:
+		// only the length is reported.
+		if _, err = io.Copy(ioutil.Discard, resp.Body); err != nil {
+			err = bodyError("Unable to read response body: %v", err)
+		}
+	case Silent:
+		// nothing but the raw body.
+		err = saveResponseBody(out, resp, "-")
+	case HttpResponseHead || HeadJSON:
+		// -I is headers only.
+		bodyRead = false
+	case Raw:
+		// the bytes as received, after the head.
+		err = saveResponseBody(out, resp, "-")
+	case Stream:
+		err = streamBody(out, resp.Body)
+	case SSE || isEventStream(resp):
+		// never ends on its own, so events are shown as they come.
+		err = showEvents(w, resp.Body)
+	case Verbose >= 2 && !PrettyJSON && largeBody(resp):
+		// the whole body would be shown, don't keep it in memory.
+		err = streamBody(out, resp.Body)
+	default:
+		// the body is read once, for whichever view shows it.
+		var body []byte
+		if body, err = readBody(resp); err == nil {
+			showBody(out, resp, body)
+		}
+	}
+	// a dropped connection leaves the body short.
+	if bodyRead && resp.ContentLength >= 0 && downloaded.n != resp.ContentLength {
+		bannerf(w, "%s\n", color.YellowString("Warning: Content-Length is %d bytes, but %d bytes were received",
+			resp.ContentLength, downloaded.n))
+	}
 	if err != nil {
-		return errors.New(color.HiRedString("failed to read response:", err))
+		return err
 	}
-	// Print SSL/TLS version which is used for connection
-	connectedVia := "plaintext"
-	if resp.TLS != nil {
-		switch resp.TLS.Version {
-		case tls.VersionTLS12:
-			connectedVia = "TLSv1.2"
-		case tls.VersionTLS13:
-			connectedVia = "TLSv1.3"
+	t.done = time.Now()
+	// trailers are only known once the body is read.
+	if bodyRead && !Silent && !HeadJSON && !OutputJSON && !Raw {
+		showTrailers(w, resp)
+	}
+	if bodyRead {
+		if err := checkDigests(w, digests); err != nil {
+			return err
 		}
 	}
-	printf("\n%s %s\n", color.GreenString("Connected via"), color.CyanString("%s", connectedVia))
 
-	// show connect-info
-	if HttpConnectInfo {
-		showRequestInfo(req)
-		printf("%s\n", grayscale(14)("*Get response from server"))
-		showResponseHeader(resp)
+	if decoded != nil {
+		bannerf(w, "%s %s\n", grayscale(14)("Decompressed:"), color.CyanString("%d -> %d bytes (%s)",
+			raw.n, decoded.n, resp.Header.Get("Content-Encoding")))
 	}
 
-	// show response head and source code
-	if HttpResponseHead {
-		if !HttpConnectInfo {
-			showResponseHeader(resp)
+	if OutputJSON {
+		if err := writeReport(out, resp, t, downloaded.n); err != nil {
+			return err
 		}
-		// this func is show full response body.
-		showResponseBody(resp)
-	} else {
-		showBriefResponse(resp)
+	}
+	if ShowTiming {
+		showTiming(w, t)
+	}
+	if ShowStats {
+		showStats(w, resp, t, downloaded.n)
+	}
+	if WriteOut != "" {
+		writeOut(out, WriteOut, resp, t, downloaded.n)
+	}
+
+	// the body is shown, but the caller still picks the exit code
+	// from the status.
+	if ExitFromStatus && resp.StatusCode >= 400 {
+		return &StatusError{Code: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// decide whether client follows a redirect.
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	w := outputOf(req.Context())
+	if !FollowRedirects || MaxRedirects == 0 {
+		// return the 3xx response as-is.
+		return http.ErrUseLastResponse
+	}
+	if Verbose >= 1 {
+		fprintf(w, "%s %s %s -> %s\n", grayscale(14)("*Redirect"), color.CyanString("%d", req.Response.StatusCode),
+			color.CyanString(via[len(via)-1].URL.String()), color.CyanString(req.URL.String()))
+	}
+	// a negative limit means no limit.
+	if MaxRedirects > 0 && len(via) > MaxRedirects {
+		return fmt.Errorf("stopped after %d redirects", MaxRedirects)
 	}
 	return nil
 }
 
+// check -X method, standard methods are normalized to uppercase.
+// Other verbs need --request-custom and are sent as given.
+func checkMethod(method string) (string, error) {
+	if method == "" || strings.IndexFunc(method, func(r rune) bool {
+		// https://datatracker.ietf.org/doc/html/rfc7230#section-3.2.6
+		return r > '~' || r <= ' ' || strings.ContainsRune("\"(),/:;<=>?@[\\]{}", r)
+	}) >= 0 {
+		return "", errors.New(color.HiRedString("Invalid HTTP method %q", method))
+	}
+	switch upper := strings.ToUpper(method); upper {
+	case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodPatch,
+		http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodConnect:
+		return upper, nil
+	}
+	if !CustomMethod {
+		return "", errors.New(color.HiRedString("Unknown HTTP method %q, use --request-custom to send it anyway", method))
+	}
+	return method, nil
+}
+
 func newRequest(method string, url *url.URL, body string) (*http.Request, error) {
-	req, err := http.NewRequest(method, url.String(), createBody(body))
+	var reader io.Reader
+	var size int64
+	var contentType string
+	var err error
+	if len(FormFields) > 0 {
+		// the parts are streamed, so the length isn't known.
+		reader, contentType, err = multipartBody(FormFields)
+		size = -1
+	} else {
+		reader, size, err = createBody(body)
+		if err == nil && body != "" {
+			reader, contentType, err = sniffBody(reader)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	// the compressed length isn't known up front, so it's sent chunked.
+	compressed := CompressRequest && size != 0
+	if compressed {
+		reader, size = gzipBody(reader), -1
+	}
+	req, err := http.NewRequest(method, url.String(), reader)
 	if err != nil {
 		return nil, errors.New(color.HiRedString("Unable to create request:", err))
 	}
-	// TODO: add headers for request
+	// the request line takes Opaque as is, a path starting with "//"
+	// has to be sent as an absolute URL then.
+	if PathAsIs && url.RawPath != "" {
+		req.URL.Opaque = url.RawPath
+		if strings.HasPrefix(url.RawPath, "//") {
+			req.URL.Opaque = "//" + url.Host + url.RawPath
+		}
+	}
+	// http.NewRequest can't know the length of a file or stdin.
+	if size >= 0 {
+		req.ContentLength = size
+	}
+	// -H may still override it.
+	switch {
+	case len(DataURLEncode) > 0:
+		contentType = formContentType
+	case JSONData != "":
+		contentType = jsonContentType
+		req.Header.Set("Accept", jsonContentType)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if SSE {
+		req.Header.Set("Accept", "text/event-stream")
+	}
+	// like curl, a large body waits for the server to accept it.
+	if size != 0 && (size < 0 || size > expect100Size) {
+		req.Header.Set("Expect", "100-continue")
+	}
+	// an empty User-Agent stops net/http from sending its default one.
+	req.Header.Set("User-Agent", UserAgent)
+
+	switch {
+	case BasicAuth != "" && BearerToken != "":
+		return nil, errors.New(color.HiRedString("-u and --bearer can't be used together"))
+	case AWSSigV4 != "" && (BasicAuth != "" || BearerToken != ""):
+		return nil, errors.New(color.HiRedString("--aws-sigv4 can't be used together with -u or --bearer"))
+	case BasicAuth != "":
+		user, pass, err := basicAuth()
+		if err != nil {
+			return nil, err
+		}
+		// --digest answers the server's challenge instead.
+		if !Digest {
+			req.SetBasicAuth(user, pass)
+		}
+	case BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+BearerToken)
+	case netrc != nil:
+		if e, ok := netrc.lookup(req.URL.Hostname()); ok && e.login != "" {
+			req.SetBasicAuth(e.login, e.password)
+		}
+	}
+
+	if ETagCompare != "" {
+		if err := addConditions(req, ETagCompare); err != nil {
+			return nil, err
+		}
+	}
+	if Range != "" {
+		req.Header.Set("Range", "bytes="+Range)
+	}
+	// setting Accept-Encoding stops net/http from decoding gzip itself.
+	if Compressed {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	if Cookie != "" {
+		if err := addCookies(req, Cookie); err != nil {
+			return nil, err
+		}
+	}
+
+	// GOURL_DEFAULT_HEADERS, then -H, override the defaults above.
+	for _, h := range strings.Split(os.Getenv(defaultHeadersEnv), ";") {
+		if strings.TrimSpace(h) == "" {
+			continue
+		}
+		if err := setHeader(req, h, false); err != nil {
+			return nil, errors.New(color.HiRedString("Bad header %q in %s, want \"Name: Value\"", h, defaultHeadersEnv))
+		}
+	}
+	for _, h := range CustomHeaders {
+		if err := setHeader(req, h, !NoExpand); err != nil {
+			return nil, errors.New(color.HiRedString("Bad header %q, want \"Name: Value\"", h))
+		}
+	}
+	if len(Trailers) > 0 {
+		if err := addTrailers(req, Trailers); err != nil {
+			return nil, err
+		}
+	}
+	if NoExpect100 {
+		req.Header.Del("Expect")
+	}
+	// the URL host is still dialed and used for TLS SNI.
+	if HostHeader != "" {
+		req.Host = HostHeader
+	}
+	// last, the signature covers the final headers.
+	if AWSSigV4 != "" {
+		if err := signSigV4(req, time.Now()); err != nil {
+			return nil, err
+		}
+	}
 	return req, nil
 }
 
-func createBody(body string) io.Reader {
-	return strings.NewReader(body)
+// bodies larger than this, or of unknown length, are sent with
+// Expect: 100-continue.
+const expect100Size = 1 << 20
+
+// environment variable of headers sent with every request, as
+// "Name: Value" pairs separated by semicolons.
+const defaultHeadersEnv = "GOURL_DEFAULT_HEADERS"
+
+// set header h, "Name: Value", on req. expand replaces ${VAR} in the
+// value from the environment.
+func setHeader(req *http.Request, h string, expand bool) error {
+	i := strings.Index(h, ":")
+	if i <= 0 {
+		return errors.New("missing header name")
+	}
+	name, value := strings.TrimSpace(h[:i]), strings.TrimSpace(h[i+1:])
+	// keep secrets like ${TOKEN} out of the shell history.
+	if expand {
+		value = os.ExpandEnv(value)
+	}
+	// net/http sends req.Host, never a Host header.
+	if http.CanonicalHeaderKey(name) == "Host" {
+		req.Host = value
+		return nil
+	}
+	req.Header.Set(name, value)
+	return nil
+}
+
+// set the --trailer "Name: Value" entries on req, which then sends its
+// body chunked.
+func addTrailers(req *http.Request, trailers []string) error {
+	// net/http sends an empty body unchunked, without trailers.
+	if req.Body == nil || req.Body == http.NoBody {
+		return errors.New(color.HiRedString("--trailer needs a request body"))
+	}
+	req.Trailer = make(http.Header, len(trailers))
+	for _, h := range trailers {
+		i := strings.Index(h, ":")
+		if i <= 0 {
+			return errors.New(color.HiRedString("Bad trailer %q, want \"Name: Value\"", h))
+		}
+		name, value := strings.TrimSpace(h[:i]), strings.TrimSpace(h[i+1:])
+		if !NoExpand {
+			value = os.ExpandEnv(value)
+		}
+		req.Trailer.Add(name, value)
+	}
+	// only a chunked body has room for trailers.
+	req.ContentLength = -1
+	return nil
+}
+
+// split -u value into user and password, asking for the password
+// on the terminal when it's missing. The answer is kept in BasicAuth
+// so we only ask once.
+func basicAuth() (string, string, error) {
+	if i := strings.Index(BasicAuth, ":"); i >= 0 {
+		return BasicAuth[:i], BasicAuth[i+1:], nil
+	}
+	user := BasicAuth
+	_, _ = fmt.Fprintf(os.Stderr, "Enter host password for user '%s':", user)
+	pass, err := term.ReadPassword(int(os.Stdin.Fd()))
+	_, _ = fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", "", errors.New(color.HiRedString("Unable to read password: %v", err))
+	}
+	BasicAuth = user + ":" + string(pass)
+	passwordPrompted = true
+	return user, string(pass), nil
+}
+
+// whether the -u password was typed at the prompt, --curl leaves it out.
+var passwordPrompted bool
+
+// explain a 401 whose WWW-Authenticate schemes we don't answer, NTLM
+// and Negotiate never, Digest only with --digest.
+func authHint(resp *http.Response) string {
+	var unsupported []string
+	for _, h := range resp.Header.Values("WWW-Authenticate") {
+		scheme, _, _ := strings.Cut(strings.TrimSpace(h), " ")
+		switch strings.ToLower(scheme) {
+		case "ntlm", "negotiate":
+			unsupported = append(unsupported, scheme)
+		case "digest":
+			if !Digest {
+				return "the server asks for Digest authentication, try --digest -u user:password"
+			}
+		}
+	}
+	if len(unsupported) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("the server asks for %s authentication, which goURL doesn't support", strings.Join(unsupported, " or "))
+}
+
+// create request body from the -d value.
+// "@file" streams the file and "@-" streams stdin, like curl.
+// The returned size is -1 when it is unknown.
+func createBody(body string) (io.Reader, int64, error) {
+	if literalBody || !strings.HasPrefix(body, "@") {
+		return strings.NewReader(body), int64(len(body)), nil
+	}
+	name := body[1:]
+	if name == "-" {
+		// a retry or another URL has to send stdin again, so keep it in memory.
+		if replayBody {
+			return bufferedStdin()
+		}
+		return os.Stdin, -1, nil
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, 0, errors.New(color.HiRedString("Unable to read body file: %v", err))
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, 0, errors.New(color.HiRedString("Unable to read body file: %v", err))
+	}
+	return f, info.Size(), nil
+}
+
+var (
+	// the body may be sent more than once.
+	replayBody bool
+
+	stdinOnce sync.Once
+	stdinBody []byte
+	stdinErr  error
+)
+
+// read stdin once, returning a new reader over it on every call.
+func bufferedStdin() (io.Reader, int64, error) {
+	stdinOnce.Do(func() {
+		stdinBody, stdinErr = ioutil.ReadAll(os.Stdin)
+	})
+	if stdinErr != nil {
+		return nil, 0, errors.New(color.HiRedString("Unable to read body from stdin: %v", stdinErr))
+	}
+	return bytes.NewReader(stdinBody), int64(len(stdinBody)), nil
+}
+
+func showRequestInfo(w io.Writer, req *http.Request, body *sentBody) {
+	if Verbose >= 2 {
+		showFullRequest(w, req)
+	} else {
+		showRequestHead(w, req)
+	}
+	if body != nil && body.n > 0 {
+		showRequestBody(w, body)
+	}
 }
 
-func showRequestInfo(req *http.Request)  {
-	printf(">%s %s\n", grayscale(14)(req.Method), grayscale(14)(req.Proto))
-	printf(">%s:%s\n", grayscale(14)("Host"), color.CyanString(req.Host))
+// show the main request headers.
+func showRequestHead(w io.Writer, req *http.Request) {
+	fprintf(w, ">%s %s\n", grayscale(14)(req.Method), grayscale(14)(req.Proto))
+	fprintf(w, ">%s:%s\n", grayscale(14)("Host"), color.CyanString(req.Host))
 	userAgent := req.UserAgent()
 	if userAgent == "" {
 		userAgent = "*"
 	}
-	printf(">%s:%s\n", grayscale(14)("User-Agent"), color.CyanString(userAgent))
+	fprintf(w, ">%s:%s\n", grayscale(14)("User-Agent"), color.CyanString(userAgent))
 	accept := req.Header.Get("Accept")
 	if accept == "" {
 		accept = "*/*"
 	}
-	printf(">%s:%s\n", grayscale(14)("Accept"), color.CyanString(accept))
+	fprintf(w, ">%s:%s\n", grayscale(14)("Accept"), color.CyanString(accept))
+	if auth := req.Header.Get("Authorization"); auth != "" {
+		// keep credentials out of the terminal and logs.
+		fprintf(w, ">%s:%s\n", grayscale(14)("Authorization"), color.CyanString(redactHeader("Authorization", auth)))
+	}
+}
+
+// show the request line, every header sent and a small body.
+func showFullRequest(w io.Writer, req *http.Request) {
+	fprintf(w, ">%s %s %s\n", grayscale(14)(req.Method), color.CyanString(req.URL.RequestURI()), grayscale(14)(req.Proto))
+	h := req.Header.Clone()
+	h.Set("Host", req.Host)
+	if req.ContentLength > 0 {
+		h.Set("Content-Length", strconv.FormatInt(req.ContentLength, 10))
+	} else if req.Body != nil && req.Body != http.NoBody {
+		h.Set("Transfer-Encoding", "chunked")
+	}
+	if len(req.Trailer) > 0 {
+		h.Set("Trailer", strings.Join(headerNames(req.Trailer), ", "))
+	}
+	// net/http doesn't send an empty User-Agent.
+	if h.Get("User-Agent") == "" {
+		h.Del("User-Agent")
+	}
+	for _, k := range headerNames(h) {
+		v := strings.Join(h[k], ",")
+		if !ShowSecrets {
+			v = redactHeader(k, v)
+		}
+		fprintf(w, ">%s %s\n", grayscale(14)(k+":"), color.CyanString(v))
+	}
+}
+
+// show the request for each of urls, head and body, as it would be sent
+// with --dry-run. Nothing is sent.
+func showDryRun(w io.Writer, method string, urls []*url.URL) error {
+	for i, u := range urls {
+		if i > 0 {
+			fprintf(w, "\n")
+		}
+		req, err := newRequest(method, u, HttpData)
+		if err != nil {
+			return err
+		}
+		showFullRequest(w, req)
+		if req.Body == nil || req.Body == http.NoBody {
+			continue
+		}
+		body := &sentBody{r: req.Body}
+		_, err = io.Copy(ioutil.Discard, body)
+		req.Body.Close()
+		if err != nil {
+			return errors.New(color.HiRedString("Unable to read body: %v", err))
+		}
+		showRequestBody(w, body)
+		for _, k := range headerNames(req.Trailer) {
+			fprintf(w, ">>%s %s\n", grayscale(14)(k+":"), color.CyanString(strings.Join(req.Trailer[k], ",")))
+		}
+	}
+	return nil
+}
+
+// hide credentials of header k, keeping only the auth scheme.
+func redactHeader(k, v string) string {
+	switch http.CanonicalHeaderKey(k) {
+	case "Authorization", "Proxy-Authorization":
+		return strings.SplitN(v, " ", 2)[0] + " [redacted]"
+	case "Cookie", "X-Amz-Security-Token":
+		return "[redacted]"
+	}
+	return v
+}
+
+func showResponseHeader(w io.Writer, resp *http.Response) {
+	for _, k := range responseHeaderNames(resp) {
+		if !headerSelected(k, resp.Header[k]) {
+			continue
+		}
+		fprintf(w, "<%s %s\n", grayscale(14)(k+":"), color.CyanString(strings.Join(resp.Header[k], ",")))
+	}
 }
 
-func showResponseHeader(resp *http.Response)  {
-	names := make([]string, 0, len(resp.Header))
-	for k := range resp.Header {
+// show the trailers received after the body, marked with "<<".
+func showTrailers(w io.Writer, resp *http.Response) {
+	for _, k := range headerNames(resp.Trailer) {
+		// announced trailers the server didn't send stay empty.
+		if len(resp.Trailer[k]) == 0 {
+			continue
+		}
+		fprintf(w, "<<%s %s\n", grayscale(14)(k+":"), color.CyanString(strings.Join(resp.Trailer[k], ",")))
+	}
+}
+
+// --grep-header, compiled by VisitURLs.
+var headerPattern *regexp.Regexp
+
+// report whether header k is shown with --header-filter and --grep-header.
+// The pattern is matched against the "Name: value" line.
+func headerSelected(k string, values []string) bool {
+	if len(HeaderFilter) > 0 {
+		found := false
+		for _, name := range HeaderFilter {
+			if strings.EqualFold(name, k) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return headerPattern == nil || headerPattern.MatchString(k+": "+strings.Join(values, ","))
+}
+
+// header names of h, sorted for showing.
+func headerNames(h http.Header) []string {
+	names := make([]string, 0, len(h))
+	for k := range h {
 		names = append(names, k)
 	}
 	sort.Sort(headers(names))
-	for _, k := range names {
-		printf("<%s %s\n", grayscale(14)(k+":"), color.CyanString(strings.Join(resp.Header[k], ",")))
+	return names
+}
+
+// write the status line and headers of resp as raw HTTP, without color.
+func writeHeaderText(w io.Writer, resp *http.Response) error {
+	if _, err := fmt.Fprintf(w, "%s %s\r\n", resp.Proto, resp.Status); err != nil {
+		return err
+	}
+	for _, k := range responseHeaderNames(resp) {
+		for _, v := range resp.Header[k] {
+			if _, err := fmt.Fprintf(w, "%s: %s\r\n", k, v); err != nil {
+				return err
+			}
+		}
+	}
+	_, err := io.WriteString(w, "\r\n")
+	return err
+}
+
+// write the response head to a file for --dump-header, "-" means w.
+func dumpHeader(w io.Writer, resp *http.Response, name string) error {
+	if name == "-" {
+		return writeHeaderText(w, resp)
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return errors.New(color.HiRedString("Unable to create header file: %v", err))
+	}
+	defer f.Close()
+	if err := writeHeaderText(f, resp); err != nil {
+		return errors.New(color.HiRedString("Unable to write header file: %v", err))
+	}
+	return nil
+}
+
+// lines shown by showBriefResponse, unless --preview-lines is given.
+const (
+	briefHead = 5
+	briefTail = 3
+)
+
+// --preview-lines HEAD[,TAIL].
+type previewLines struct {
+	head, tail int
+}
+
+func (p *previewLines) String() string { return fmt.Sprintf("%d,%d", p.head, p.tail) }
+
+func (p *previewLines) Set(v string) error {
+	parts := strings.SplitN(v, ",", 2)
+	head, err := strconv.Atoi(parts[0])
+	tail := 0
+	if err == nil && len(parts) == 2 {
+		tail, err = strconv.Atoi(parts[1])
 	}
+	if err != nil || head < 0 || tail < 0 {
+		return errors.New("want HEAD or HEAD,TAIL line counts")
+	}
+	p.head, p.tail = head, tail
+	return nil
+}
+
+// show the full body under -vv and -j, a brief one otherwise.
+func showBody(w io.Writer, resp *http.Response, body []byte) {
+	if Verbose >= 2 || PrettyJSON {
+		showResponseBody(w, resp, body)
+		return
+	}
+	showBriefResponse(w, body)
 }
 
 // show brief response body.
-func showBriefResponse(resp *http.Response)  {
-	s, _ := ioutil.ReadAll(resp.Body)
-	body := strings.Split(string(s), "\n")
-	// we only show first and last five lines.
-	show := append(body[:5], body[len(body) - 3:]...)
-	printf("%s", grayscale(14)("Body:"))
+func showBriefResponse(w io.Writer, s []byte) {
+	if PreviewBytes > 0 {
+		showBodyBytes(w, s, int(PreviewBytes))
+		return
+	}
+	// a final newline ends the last line, it doesn't start another.
+	body := strings.Split(strings.TrimSuffix(string(s), "\n"), "\n")
+	head, tail := PreviewLines.head, PreviewLines.tail
+	// we only show the first head and the last tail lines.
+	// short bodies are shown entirely, so head and tail never overlap.
+	show := body
+	if len(body) > head+tail {
+		// copy into a new slice, appending to body[:head] would
+		// overwrite the lines after it.
+		show = make([]string, 0, head+tail)
+		show = append(show, body[:head]...)
+		show = append(show, body[len(body)-tail:]...)
+	}
+	fprintf(w, "%s", grayscale(14)("Body:"))
 	for _, s := range show {
-		printf("%s\n", color.CyanString(s))
+		fprintf(w, "%s\n", color.CyanString(truncateLine(s)))
+	}
+}
+
+// longest body line shown, set by VisitURLs from --max-line-length.
+// Zero or less shows lines whole.
+var lineLength int
+
+// width of the terminal on stdout, 0 when it isn't one.
+func terminalWidth() int {
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0
+	}
+	width, _, err := term.GetSize(fd)
+	if err != nil {
+		return 0
+	}
+	return width
+}
+
+// cut s to lineLength characters, ending with an ellipsis. Color escapes
+// take no room and are never split, a cut colored line ends with a reset.
+func truncateLine(s string) string {
+	if lineLength <= 0 || visibleLength(s) <= lineLength {
+		return s
+	}
+	var b strings.Builder
+	n, colored := 0, false
+	for i := 0; i < len(s); {
+		if end := escapeEnd(s, i); end > i {
+			b.WriteString(s[i:end])
+			i, colored = end, true
+			continue
+		}
+		if n == lineLength-1 {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		b.WriteString(s[i : i+size])
+		i += size
+		n++
+	}
+	b.WriteString("…")
+	if colored {
+		b.WriteString("\x1b[0m")
+	}
+	return b.String()
+}
+
+// characters of s on screen, without color escapes.
+func visibleLength(s string) int {
+	n := 0
+	for i := 0; i < len(s); {
+		if end := escapeEnd(s, i); end > i {
+			i = end
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		n++
+	}
+	return n
+}
+
+// end of the "ESC [ ... m"-like escape sequence starting at s[i], or i
+// when there is none.
+func escapeEnd(s string, i int) int {
+	if i+1 >= len(s) || s[i] != '\x1b' || s[i+1] != '[' {
+		return i
+	}
+	for j := i + 2; j < len(s); j++ {
+		if 0x40 <= s[j] && s[j] <= 0x7e {
+			return j + 1
+		}
+	}
+	return i
+}
+
+// truncate each line of s.
+func truncateLines(s string) string {
+	if lineLength <= 0 {
+		return s
+	}
+	lines := strings.Split(s, "\n")
+	for i := range lines {
+		lines[i] = truncateLine(lines[i])
 	}
+	return strings.Join(lines, "\n")
+}
+
+// show the first n bytes of body for --preview-bytes.
+func showBodyBytes(w io.Writer, body []byte, n int) {
+	fprintf(w, "%s", grayscale(14)("Body:"))
+	if len(body) <= n {
+		fprintf(w, "%s\n", color.CyanString(string(body)))
+		return
+	}
+	fprintf(w, "%s\n", color.CyanString(string(body[:n])))
+	fprintf(w, "%s\n", grayscale(14)("[%d more bytes]", len(body)-n))
 }
 
 // Show full response.
-func showResponseBody(resp *http.Response)  {
-	s, _ := ioutil.ReadAll(resp.Body)
-	printf("%s %s\n", grayscale(14)("Body:"), color.CyanString(string(s)))
-}
\ No newline at end of file
+func showResponseBody(w io.Writer, resp *http.Response, s []byte) {
+	// malformed JSON is still shown raw.
+	if PrettyJSON && isJSON(resp) {
+		if pretty, ok := prettyJSON(s); ok {
+			fprintf(w, "%s\n%s\n", grayscale(14)("Body:"), truncateLines(pretty))
+			return
+		}
+	}
+	fprintf(w, "%s %s\n", grayscale(14)("Body:"), color.CyanString(truncateLines(string(s))))
+}
+
+// bodies larger than this, or of unknown length, are streamed under -vv.
+const streamSize = 1 << 20
+
+// report whether resp is too large, or may be, to read into memory.
+func largeBody(resp *http.Response) bool {
+	return resp.ContentLength < 0 || resp.ContentLength > streamSize
+}
+
+// copy body to w as it arrives, without charset decoding.
+func streamBody(w io.Writer, body io.Reader) error {
+	fprintf(w, "%s ", grayscale(14)("Body:"))
+	_, err := io.Copy(cyanWriter{w}, body)
+	fprintf(w, "\n")
+	if err != nil {
+		return bodyError("Unable to read response body: %v", err)
+	}
+	return nil
+}
+
+// writer coloring what it writes like a shown body.
+type cyanWriter struct {
+	w io.Writer
+}
+
+func (c cyanWriter) Write(p []byte) (int, error) {
+	if _, err := io.WriteString(c.w, color.CyanString("%s", p)); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// read the whole body for showing it.
+func readBody(resp *http.Response) ([]byte, error) {
+	s, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, bodyError("Unable to read response body: %v", err)
+	}
+	return decodeCharset(resp, s), nil
+}
+
+// error of reading or saving a response body. --max-time and
+// --max-filesize stopping it are told as such.
+func bodyError(format string, err error) error {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, errMaxFileSize):
+		return errors.New(color.HiRedString("%v", err))
+	case MaxTime > 0 && (errors.Is(err, context.DeadlineExceeded) || errors.As(err, &netErr) && netErr.Timeout()):
+		return errors.New(color.HiRedString("Operation timed out after %v", MaxTime))
+	}
+	return errors.New(color.HiRedString(format, err))
+}
+
+// write response body to a file, "-" means w.
+func saveResponseBody(w io.Writer, resp *http.Response, name string) error {
+	if name == "-" {
+		if _, err := io.Copy(w, resp.Body); err != nil {
+			return bodyError("Unable to write output: %v", err)
+		}
+		return nil
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if AppendOutput {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+	f, err := os.OpenFile(name, flags, 0666)
+	if err != nil {
+		return errors.New(color.HiRedString("Unable to create output file: %v", err))
+	}
+	// size before this body, what a failed --append goes back to.
+	start, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		f.Close()
+		return errors.New(color.HiRedString("Unable to create output file: %v", err))
+	}
+	dst := io.Writer(f)
+	// progress goes to stderr, and would garble parallel output.
+	var bar *progress
+	if !Silent && Parallel <= 1 {
+		total := resp.ContentLength
+		// Content-Length is the size before --compressed decoding.
+		if Compressed && resp.Header.Get("Content-Encoding") != "" {
+			total = -1
+		}
+		bar = newProgress(os.Stderr, total)
+		dst = io.MultiWriter(f, bar)
+	}
+	n, err := io.Copy(dst, resp.Body)
+	if bar != nil {
+		bar.finish()
+	}
+	if err != nil && start > 0 {
+		// keep what was there before.
+		_ = f.Truncate(start)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		// don't leave a partial file behind.
+		if start == 0 {
+			_ = os.Remove(name)
+		}
+		return bodyError("Unable to write output file: %v", err)
+	}
+	bannerf(w, "%s %s\n", grayscale(14)("Saved:"), color.CyanString("%d bytes to %s", n, name))
+	return nil
+}
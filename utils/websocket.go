@@ -0,0 +1,300 @@
+package utils
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/fatih/color"
+)
+
+// from RFC 6455, hashed with the key into Sec-WebSocket-Accept.
+const wsGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// websocket opcodes.
+const (
+	wsContinuation = 0x0
+	wsText         = 0x1
+	wsBinary       = 0x2
+	wsClose        = 0x8
+	wsPing         = 0x9
+	wsPong         = 0xa
+)
+
+// report whether u is fetched as a WebSocket, by scheme or --ws.
+func isWebSocket(u *url.URL) bool {
+	return WebSocket || u.Scheme == "ws" || u.Scheme == "wss"
+}
+
+// upgrade to a WebSocket, show the 101 response, then send each line
+// of stdin as a text message and print the messages received until
+// stdin ends or the server closes.
+func visitWebSocket(w io.Writer, client *http.Client, u *url.URL) error {
+	client, err := http1Client(client)
+	if err != nil {
+		return err
+	}
+	httpURL := *u
+	switch u.Scheme {
+	case "ws":
+		httpURL.Scheme = "http"
+	case "wss":
+		httpURL.Scheme = "https"
+	}
+	req, err := newRequest(http.MethodGet, &httpURL, "")
+	if err != nil {
+		return err
+	}
+	key, err := wsKey()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req = req.WithContext(context.WithValue(context.Background(), outputKey{}, w))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return requestError(err, &timing{})
+	}
+	defer resp.Body.Close()
+	if !Silent {
+		fprintf(w, "%s %s\n", grayscale(14)(resp.Proto), color.CyanString(resp.Status))
+		showResponseHeader(w, resp)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return errors.New(color.HiRedString("Server refused the WebSocket upgrade: %s", resp.Status))
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != wsAccept(key) {
+		return errors.New(color.HiRedString("Bad Sec-WebSocket-Accept from server"))
+	}
+	conn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return errors.New(color.HiRedString("Connection can't be used as a WebSocket"))
+	}
+	bannerf(w, "%s\n", color.GreenString("WebSocket open, each line of stdin is sent as a message"))
+
+	ws := &wsConn{rw: conn}
+	done := make(chan error, 1)
+	go func() {
+		done <- ws.receive(w)
+	}()
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(os.Stdin)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	for {
+		select {
+		case err := <-done:
+			return err
+		case line, ok := <-lines:
+			if !ok {
+				// say goodbye and wait a little for the server's close.
+				if err := ws.write(wsClose, []byte{0x03, 0xe8}); err != nil {
+					return err
+				}
+				select {
+				case err := <-done:
+					return err
+				case <-time.After(2 * time.Second):
+					return nil
+				}
+			}
+			if err := ws.write(wsText, []byte(line)); err != nil {
+				return errors.New(color.HiRedString("Unable to send message: %v", err))
+			}
+		}
+	}
+}
+
+// copy of client for the upgrade, which needs HTTP/1.1: HTTP/2 has no
+// Connection header. The other URLs keep HTTP/2.
+func http1Client(client *http.Client) (*http.Client, error) {
+	tr, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return nil, errors.New(color.HiRedString("--http3 can't be used for a WebSocket"))
+	}
+	if HTTP11 {
+		return client, nil
+	}
+	tr = tr.Clone()
+	tr.ForceAttemptHTTP2 = false
+	tr.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	// the shared config may offer h2 by now.
+	if tr.TLSClientConfig != nil {
+		tr.TLSClientConfig.NextProtos = nil
+	}
+	c := *client
+	c.Transport = tr
+	return &c, nil
+}
+
+// random Sec-WebSocket-Key.
+func wsKey() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(b), nil
+}
+
+// Sec-WebSocket-Accept the server must answer for key.
+func wsAccept(key string) string {
+	h := sha1.Sum([]byte(key + wsGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+// client side of a WebSocket, frames are written masked.
+type wsConn struct {
+	rw      io.ReadWriter
+	mu      sync.Mutex // one frame written at a time
+	closing bool       // our close frame is sent
+}
+
+// write one final frame.
+func (c *wsConn) write(opcode byte, payload []byte) error {
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, 0x80|byte(n))
+	case n <= 0xffff:
+		header = append(header, 0x80|126, 0, 0)
+		binary.BigEndian.PutUint16(header[2:], uint16(n))
+	default:
+		header = append(header, 0x80|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(header[2:], uint64(n))
+	}
+	mask := make([]byte, 4)
+	if _, err := rand.Read(mask); err != nil {
+		return err
+	}
+	header = append(header, mask...)
+	masked := make([]byte, len(payload))
+	for i, b := range payload {
+		masked[i] = b ^ mask[i%4]
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if opcode == wsClose {
+		c.closing = true
+	}
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+	_, err := c.rw.Write(masked)
+	return err
+}
+
+// read one frame.
+func (c *wsConn) readFrame() (fin bool, opcode byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.rw, head[:]); err != nil {
+		return
+	}
+	fin, opcode = head[0]&0x80 != 0, head[0]&0x0f
+	n := uint64(head[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.rw, ext[:]); err != nil {
+			return
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.rw, ext[:]); err != nil {
+			return
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	var mask []byte
+	if head[1]&0x80 != 0 {
+		mask = make([]byte, 4)
+		if _, err = io.ReadFull(c.rw, mask); err != nil {
+			return
+		}
+	}
+	if MaxFileSize > 0 && n > uint64(MaxFileSize) {
+		err = fmt.Errorf("%w (%d bytes)", errMaxFileSize, int64(MaxFileSize))
+		return
+	}
+	payload = make([]byte, n)
+	if _, err = io.ReadFull(c.rw, payload); err != nil {
+		return
+	}
+	if mask != nil {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return
+}
+
+// print received messages until the server closes, answering pings.
+func (c *wsConn) receive(w io.Writer) error {
+	var message []byte
+	var kind byte
+	for {
+		fin, opcode, payload, err := c.readFrame()
+		if err != nil {
+			// some servers hang up without answering our close.
+			c.mu.Lock()
+			closing := c.closing
+			c.mu.Unlock()
+			if closing {
+				return nil
+			}
+			return errors.New(color.HiRedString("WebSocket closed: %v", err))
+		}
+		switch opcode {
+		case wsPing:
+			if err := c.write(wsPong, payload); err != nil {
+				return err
+			}
+			continue
+		case wsPong:
+			continue
+		case wsClose:
+			code := 1005
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			bannerf(w, "%s %s\n", color.GreenString("WebSocket closed by server:"), color.CyanString("%d %s", code, payload[min(2, len(payload)):]))
+			return nil
+		case wsText, wsBinary:
+			kind, message = opcode, payload
+		case wsContinuation:
+			message = append(message, payload...)
+		}
+		if !fin {
+			continue
+		}
+		if kind == wsText && utf8.Valid(message) {
+			fprintf(w, "< %s\n", color.CyanString(string(message)))
+		} else {
+			fprintf(w, "< %s\n", grayscale(14)("[%d bytes of binary data]", len(message)))
+		}
+		message = nil
+	}
+}
@@ -0,0 +1,98 @@
+package utils
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// print the -w format, expanding curl style %{variable}s
+// and \n, \r, \t escapes.
+func writeOut(w io.Writer, format string, resp *http.Response, t *timing, downloaded int64) {
+	seconds := func(d time.Duration) string { return fmt.Sprintf("%.6f", d.Seconds()) }
+	vars := map[string]func() string{
+		"http_code":         func() string { return fmt.Sprintf("%03d", resp.StatusCode) },
+		"time_total":        func() string { return seconds(t.total()) },
+		"time_namelookup":   func() string { return seconds(between(t.start, t.dnsDone)) },
+		"time_connect":      func() string { return seconds(between(t.start, t.connectDone)) },
+		"size_download":     func() string { return fmt.Sprint(downloaded) },
+		"remote_ip":         func() string { return remoteIP(t.remoteAddr) },
+		"ssl_verify_result": func() string { return fmt.Sprint(sslVerifyResult(resp)) },
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		switch {
+		case c == '%' && strings.HasPrefix(format[i:], "%{"):
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				b.WriteString(format[i:])
+				i = len(format)
+				break
+			}
+			// unknown variables expand to nothing, like curl.
+			if v, ok := vars[format[i+2:i+end]]; ok {
+				b.WriteString(v())
+			}
+			i += end
+		case c == '%' && strings.HasPrefix(format[i:], "%%"):
+			b.WriteByte('%')
+			i++
+		case c == '\\' && i+1 < len(format):
+			switch format[i+1] {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case '\\':
+				b.WriteByte('\\')
+			default:
+				b.WriteString(format[i : i+2])
+			}
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	_, _ = io.WriteString(w, b.String())
+}
+
+// IP part of a "host:port" address.
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// 0 when the server certificate verifies (or for plaintext), 1 otherwise.
+// With -k the handshake skips verification, so check the chain here.
+func sslVerifyResult(resp *http.Response) int {
+	if resp.TLS == nil || !InsecureSkip {
+		return 0
+	}
+	certs := resp.TLS.PeerCertificates
+	if len(certs) == 0 {
+		return 1
+	}
+	opts := x509.VerifyOptions{
+		DNSName:       resp.Request.URL.Hostname(),
+		Roots:         rootCAs,
+		Intermediates: x509.NewCertPool(),
+	}
+	for _, c := range certs[1:] {
+		opts.Intermediates.AddCert(c)
+	}
+	if _, err := certs[0].Verify(opts); err != nil {
+		return 1
+	}
+	return 0
+}